@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/configs"
+	"github.com/nathanfernande/golang-mongodb-api/controllers"
+	"github.com/nathanfernande/golang-mongodb-api/metrics"
+	"github.com/nathanfernande/golang-mongodb-api/middleware"
+	"github.com/nathanfernande/golang-mongodb-api/openapi"
+	"github.com/nathanfernande/golang-mongodb-api/policy"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/routes"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"github.com/nathanfernande/golang-mongodb-api/tracing"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+//App é a aplicação montada: dona do cliente do MongoDB, do servidor Fiber e do TracerProvider.
+//É criada por NewApp, atende requisições com Run e libera os recursos ao final de Run.
+type App struct {
+	config  configs.Config
+	client  *mongo.Client
+	server  *fiber.App
+	health  *controllers.HealthController
+	metrics *metrics.Metrics
+	tracer  *sdktrace.TracerProvider
+}
+
+//NewApp conecta ao MongoDB, cria os índices, carrega as chaves e as políticas e registra as rotas.
+//Se alguma etapa falhar, os recursos já criados são liberados antes de retornar o erro.
+func NewApp(config configs.Config) (*App, error) {
+	tracer, err := tracing.NewProvider(context.Background(), config.Tracing.Exporter, config.Tracing.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	app := &App{config: config, server: fiber.New(serverConfig(config.Server)), metrics: metrics.New(), tracer: tracer}
+
+	//os monitores do driver alimentam as métricas de comandos e do pool de conexões do MongoDB
+	//e criam um span para cada comando, filho do span da requisição
+	commandMonitor := combineCommandMonitors(app.metrics.CommandMonitor(), tracing.CommandMonitor(tracer))
+	client, err := configs.ConnectDB(config.Mongo, options.Client().SetMonitor(commandMonitor).SetPoolMonitor(app.metrics.PoolMonitor()))
+	if err != nil {
+		return nil, errors.Join(err, app.close())
+	}
+	app.client = client
+	//GET /readyz só responde 200 depois que o servidor está escutando e enquanto o MongoDB responde ao ping
+	app.health = controllers.NewHealthController(config.Server.ReadinessTimeout, controllers.Dependency{
+		Name:  "mongo",
+		Check: func(ctx context.Context) error { return client.Ping(ctx, readpref.Primary()) },
+	})
+	app.server.Hooks().OnListen(func(data fiber.ListenData) error {
+		slog.Info("listening", "addr", data.Host+":"+data.Port)
+		app.health.SetReady(true)
+		return nil
+	})
+	if err := app.setup(); err != nil {
+		return nil, errors.Join(err, app.close())
+	}
+	return app, nil
+}
+
+//Configuração do servidor Fiber. Com server.proxyHeader, c.IP() (usado por LimitByIP e pelo log de acesso) vem desse cabeçalho,
+//mas só em requisições dos endereços de server.trustedProxies; nas demais, e sem proxyHeader, é o endereço da conexão.
+func serverConfig(server configs.ServerConfig) fiber.Config {
+	return fiber.Config{
+		DisableStartupMessage:   true,
+		ErrorHandler:            problems.ErrorHandler(server.Production()),
+		ProxyHeader:             server.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          server.TrustedProxies,
+		EnableIPValidation:      true,
+	}
+}
+
+//Stores do MongoDB que precisam criar seus índices na inicialização.
+type indexedStore interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+//Cria os stores e os middlewares e registra as rotas no servidor.
+func (a *App) setup() error {
+	//o store de usuários usa a coleção "users" e a trilha de auditoria fica na coleção separada "user_audit"
+	database := a.config.Mongo.Database
+	userStore := stores.NewMongoUserStore(configs.GetCollection(a.client, database, "users"))
+	auditStore := stores.NewMongoAuditStore(configs.GetCollection(a.client, database, "user_audit"))
+	apiKeyStore := stores.NewMongoAPIKeyStore(configs.GetCollection(a.client, database, "api_keys"))
+	rateLimitStore := stores.NewMongoRateLimitStore(configs.GetCollection(a.client, database, "rate_limits"))
+	idempotencyStore := stores.NewMongoIdempotencyStore(configs.GetCollection(a.client, database, "idempotency_keys"))
+
+	//cria os índices das coleções (incluindo o índice de texto usado por GET /users/search)
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.Mongo.ConnectTimeout)
+	defer cancel()
+	for _, store := range []indexedStore{userStore, auditStore, apiKeyStore, rateLimitStore, idempotencyStore} {
+		if err := store.EnsureIndexes(ctx); err != nil {
+			return err
+		}
+	}
+
+	//as rotas exigem um token JWT assinado por uma das chaves do arquivo JWKS
+	//ou uma chave de API (cabeçalho X-API-Key) com o scope da rota
+	keys, err := middleware.LoadKeySet(a.config.Auth.JWTKeysFile)
+	if err != nil {
+		return err
+	}
+	//cada IP tem um orçamento consumido antes da autenticação, para que credenciais inválidas também sejam limitadas
+	server := a.config.Server
+	authenticate := middleware.LimitByIP(rateLimitStore, stores.RateLimit(a.config.RateLimit.IP), a.config.RateLimit.Timeout,
+		middleware.Authenticate(middleware.JWT(keys), middleware.APIKey(apiKeyStore, server.RequestTimeout)))
+
+	//as políticas de acesso (papéis e regras) decidem o que cada token JWT pode fazer
+	policies, err := policy.Load(a.config.Auth.PolicyFile)
+	if err != nil {
+		return err
+	}
+	authorize := middleware.Authorizer(policies, userStore, server.RequestTimeout)
+
+	//cada cliente tem orçamentos separados de leitura e de escrita, guardados no MongoDB
+	//para que todas as réplicas da API compartilhem os mesmos limites
+	rateLimit := middleware.RateLimiter(rateLimitStore, stores.RateLimit(a.config.RateLimit.Read), stores.RateLimit(a.config.RateLimit.Write), a.config.RateLimit.Timeout)
+
+	//as respostas de POST /user com Idempotency-Key são guardadas para que repetições não criem usuários duplicados
+	idempotent := middleware.Idempotency(idempotencyStore, a.config.Idempotency.TTL, server.RequestTimeout)
+
+	//rotas; os middlewares de ID da requisição, tracing, log de acesso e métricas vêm antes de todas para cobrir
+	//também as respostas de autenticação e do limitador. As sondas e o scrape de métricas só aparecem no log com nível debug.
+	a.server.Use(
+		middleware.RequestID(),
+		middleware.Tracing(a.tracer),
+		middleware.AccessLog("/healthz", "/readyz", "/metrics"),
+		middleware.Metrics(a.metrics),
+	)
+	spec, err := openapi.Spec()
+	if err != nil {
+		return err
+	}
+	timeouts := controllers.Timeouts{Request: server.RequestTimeout, Bulk: server.BulkTimeout, Import: server.ImportTimeout}
+	routes.Register(a.server, routes.Dependencies{
+		Health:       a.health,
+		Metrics:      a.metrics.Handler(),
+		Spec:         spec,
+		DocsPage:     openapi.DocsPage(),
+		DocsAssets:   openapi.DocsAssets(),
+		Users:        controllers.NewUserController(userStore, auditStore, a.config.Users.Retention, timeouts),
+		APIKeys:      controllers.NewAPIKeyController(apiKeyStore, server.RequestTimeout),
+		Authenticate: authenticate,
+		RateLimit:    rateLimit,
+		Authorize:    authorize,
+		Idempotent:   idempotent,
+	})
+	return nil
+}
+
+//Run inicia o servidor HTTP no endereço configurado e bloqueia até ctx ser cancelado (ex.: SIGINT ou SIGTERM)
+//ou o servidor falhar. No cancelamento, GET /readyz passa a responder 503 e, depois de server.drainDelay,
+//o servidor para de aceitar conexões, espera as requisições em andamento terminarem (até server.shutdownTimeout)
+//e só então desconecta do MongoDB, que essas requisições ainda usam.
+//Retorna nil em um encerramento limpo.
+func (a *App) Run(ctx context.Context) error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- a.server.Listen(a.config.Server.Addr)
+	}()
+
+	select {
+	case err := <-listenErr:
+		//o servidor não chegou a subir (ex.: porta em uso) ou parou sozinho
+		return errors.Join(err, a.close())
+	case <-ctx.Done():
+	}
+
+	a.health.SetReady(false)
+	if delay := a.config.Server.DrainDelay; delay > 0 {
+		slog.Info("shutting down: draining", "delay", delay.String())
+		time.Sleep(delay)
+	}
+	slog.Info("shutting down: waiting for in-flight requests", "timeout", a.config.Server.ShutdownTimeout.String())
+	shutdownErr := a.server.ShutdownWithTimeout(a.config.Server.ShutdownTimeout)
+	//Listen retorna assim que o listener é fechado; o erro dele não interessa mais aqui.
+	<-listenErr
+	return errors.Join(shutdownErr, a.close())
+}
+
+//Desconecta do MongoDB, fechando as conexões do pool, e envia os spans que ainda estão no TracerProvider.
+func (a *App) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.Mongo.ConnectTimeout)
+	defer cancel()
+	var err error
+	if a.client != nil {
+		err = a.client.Disconnect(ctx)
+	}
+	return errors.Join(err, a.tracer.Shutdown(ctx))
+}
+
+//Junta vários monitores de comandos em um só, já que o cliente do MongoDB aceita apenas um.
+func combineCommandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
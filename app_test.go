@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/configs"
+	"github.com/nathanfernande/golang-mongodb-api/controllers"
+	"go.mongodb.org/mongo-driver/event"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+//Monta um App sem MongoDB, com GET /readyz e GET /slow, que espera release antes de responder.
+//O endereço em que o servidor passa a escutar é enviado em listening, e só então a aplicação fica pronta, como em NewApp.
+func newLifecycleTestApp(t *testing.T, server configs.ServerConfig) (app *App, listening <-chan string, started <-chan struct{}, release chan<- struct{}) {
+	t.Helper()
+	config := configs.Default()
+	config.Server = server
+	app = &App{config: config, server: fiber.New(serverConfig(server)), tracer: sdktrace.NewTracerProvider()}
+	app.health = controllers.NewHealthController(time.Second)
+
+	listen, start, done := make(chan string, 1), make(chan struct{}, 1), make(chan struct{})
+	app.server.Hooks().OnListen(func(data fiber.ListenData) error {
+		app.health.SetReady(true)
+		listen <- net.JoinHostPort(data.Host, data.Port)
+		return nil
+	})
+	app.server.Get("/readyz", app.health.Readiness)
+	app.server.Get("/slow", func(c *fiber.Ctx) error {
+		start <- struct{}{}
+		<-done
+		return c.SendString("done")
+	})
+	return app, listen, start, done
+}
+
+func TestServerConfigClientIP(t *testing.T) {
+	//app.Test envia as requisições a partir de 0.0.0.0.
+	tests := []struct {
+		name   string
+		server configs.ServerConfig
+		header string
+		want   string
+	}{
+		{"no proxy header", configs.ServerConfig{}, "203.0.113.7", "0.0.0.0"},
+		{"trusted proxy", configs.ServerConfig{ProxyHeader: "X-Real-IP", TrustedProxies: []string{"0.0.0.0"}}, "203.0.113.7", "203.0.113.7"},
+		{"trusted proxy network", configs.ServerConfig{ProxyHeader: "X-Real-IP", TrustedProxies: []string{"0.0.0.0/8"}}, "203.0.113.7", "203.0.113.7"},
+		{"untrusted proxy", configs.ServerConfig{ProxyHeader: "X-Real-IP", TrustedProxies: []string{"10.0.0.0/8"}}, "203.0.113.7", "0.0.0.0"},
+		{"list uses the first valid IP", configs.ServerConfig{ProxyHeader: "X-Real-IP", TrustedProxies: []string{"0.0.0.0"}}, "unknown, 203.0.113.7, 10.0.0.1", "203.0.113.7"},
+		{"invalid header", configs.ServerConfig{ProxyHeader: "X-Real-IP", TrustedProxies: []string{"0.0.0.0"}}, "not-an-ip", "0.0.0.0"},
+	}
+	for _, test := range tests {
+		app := fiber.New(serverConfig(test.server))
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendString(c.IP()) })
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", test.header)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != test.want {
+			t.Errorf("%s: c.IP() = %q, want %q", test.name, body, test.want)
+		}
+	}
+}
+
+func TestRunGracefulShutdown(t *testing.T) {
+	app, listening, started, release := newLifecycleTestApp(t, configs.ServerConfig{Addr: "127.0.0.1:0", ShutdownTimeout: 5 * time.Second, DrainDelay: 300 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- app.Run(ctx) }()
+
+	var addr string
+	select {
+	case addr = <-listening:
+	case err := <-runErr:
+		t.Fatalf("Run returned before listening: %v", err)
+	}
+	get := func(path string) (int, error) {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		_, err = io.ReadAll(resp.Body)
+		return resp.StatusCode, err
+	}
+	if status, err := get("/readyz"); err != nil || status != http.StatusOK {
+		t.Fatalf("GET /readyz before shutdown = %d, %v, want 200", status, err)
+	}
+
+	//Uma requisição em andamento quando o encerramento começa.
+	slow := make(chan int, 1)
+	go func() {
+		status, err := get("/slow")
+		if err != nil {
+			t.Errorf("in-flight request failed: %v", err)
+		}
+		slow <- status
+	}()
+	<-started
+	cancel()
+
+	//Durante o drainDelay o servidor continua atendendo, mas /readyz responde 503.
+	deadline := time.Now().Add(time.Second)
+	for {
+		status, err := get("/readyz")
+		if err != nil {
+			t.Fatalf("GET /readyz while draining: %v", err)
+		}
+		if status == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET /readyz while draining = %d, want 503", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//O encerramento espera a requisição em andamento terminar.
+	select {
+	case err := <-runErr:
+		t.Fatalf("Run returned with a request in flight: %v", err)
+	case <-time.After(600 * time.Millisecond):
+	}
+	close(release)
+	if status := <-slow; status != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want 200", status)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("Run = %v, want nil after a clean shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the in-flight request finished")
+	}
+	if _, err := get("/readyz"); err == nil {
+		t.Error("server still accepts connections after Run returned")
+	}
+}
+
+func TestRunListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	//Com a porta ocupada, Run retorna o erro do Listen sem esperar o contexto.
+	app, _, _, _ := newLifecycleTestApp(t, configs.ServerConfig{Addr: listener.Addr().String(), ShutdownTimeout: time.Second})
+	runErr := make(chan error, 1)
+	go func() { runErr <- app.Run(context.Background()) }()
+	select {
+	case err := <-runErr:
+		if err == nil {
+			t.Error("Run = nil, want the listen error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after failing to listen")
+	}
+}
+
+func TestCombineCommandMonitors(t *testing.T) {
+	var calls []string
+	record := func(name string) *event.CommandMonitor {
+		return &event.CommandMonitor{
+			Started:   func(context.Context, *event.CommandStartedEvent) { calls = append(calls, name+" started") },
+			Succeeded: func(context.Context, *event.CommandSucceededEvent) { calls = append(calls, name+" succeeded") },
+			Failed:    func(context.Context, *event.CommandFailedEvent) { calls = append(calls, name+" failed") },
+		}
+	}
+	//Monitores sem algum dos callbacks são aceitos.
+	monitor := combineCommandMonitors(record("metrics"), &event.CommandMonitor{}, record("tracing"))
+	monitor.Started(context.Background(), &event.CommandStartedEvent{})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{})
+
+	want := []string{"metrics started", "tracing started", "metrics succeeded", "tracing succeeded", "metrics failed", "tracing failed"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls = %v, want %v", calls, want)
+			break
+		}
+	}
+}
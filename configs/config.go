@@ -0,0 +1,478 @@
+package configs
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "io/fs"
+    "net"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/joho/godotenv" // Pacote que ajuda a carregar variáveis de ambiente a partir de um arquivo .env
+    "github.com/nathanfernande/golang-mongodb-api/logging"
+    "github.com/nathanfernande/golang-mongodb-api/tracing"
+    "github.com/pelletier/go-toml/v2"
+    "gopkg.in/yaml.v3"
+)
+
+//Config reúne toda a configuração da aplicação.
+//Os valores são carregados por Load, nesta ordem (cada fonte sobrescreve a anterior):
+//valores padrão, arquivo de configuração (YAML ou TOML), variáveis de ambiente e flags da linha de comando.
+type Config struct {
+    Mongo       MongoConfig
+    Server      ServerConfig
+    Auth        AuthConfig
+    Users       UsersConfig
+    RateLimit   RateLimitConfig
+    Idempotency IdempotencyConfig
+    Tracing     TracingConfig
+    Log         LogConfig
+
+    //PrintConfig é true quando o programa foi iniciado com --print-config: a configuração deve ser impressa (com Print) e o programa encerrado.
+    //Nesse caso Load não exige os campos obrigatórios, mas continua recusando valores inválidos.
+    PrintConfig bool
+}
+
+//MongoConfig configura a conexão com o MongoDB.
+type MongoConfig struct {
+    //URI é a string de conexão. Pode conter usuário e senha, por isso é ocultada por Print.
+    URI      string
+    Database string
+    //ConnectTimeout limita a conexão inicial, o ping, a criação dos índices e a desconexão no encerramento.
+    ConnectTimeout time.Duration
+}
+
+//ServerConfig configura o servidor HTTP e os tempos limite dos handlers.
+type ServerConfig struct {
+    //Addr é o endereço em que o servidor escuta (ex.: ":6000").
+    Addr string
+    //RequestTimeout limita as operações no banco de uma requisição comum (um usuário, uma página ou uma chave de API)
+    //e cada consulta dos middlewares de autenticação, autorização e idempotência.
+    RequestTimeout time.Duration
+    //BulkTimeout limita POST /users/bulk e ImportTimeout limita POST /users/import, que podem gravar muitos usuários.
+    BulkTimeout   time.Duration
+    ImportTimeout time.Duration
+    //ShutdownTimeout é quanto o servidor espera as requisições em andamento terminarem ao receber SIGINT ou SIGTERM.
+    ShutdownTimeout time.Duration
+    //DrainDelay é quanto o servidor continua aceitando requisições depois de GET /readyz passar a responder 503,
+    //para que o balanceador de carga pare de enviar tráfego antes de as conexões serem recusadas.
+    DrainDelay time.Duration
+    //ReadinessTimeout limita cada verificação de dependência (ex.: o ping do MongoDB) de GET /readyz.
+    ReadinessTimeout time.Duration
+    //Mode é "production" ou "development". Em produção, as respostas de erro não incluem a causa das falhas internas
+    //(ex.: mensagens do driver do MongoDB), que continua indo para o log.
+    Mode string
+    //ProxyHeader é o cabeçalho com o IP do cliente quando a API fica atrás de um proxy reverso ou balanceador de carga
+    //(ex.: X-Real-IP). O IP do cliente é usado pelo limite por IP (rateLimit.ip) e pelo log de acesso; vazio usa o endereço da conexão.
+    //O proxy precisa definir o cabeçalho em vez de acrescentar ao valor do cliente: com vários IPs, vale o primeiro válido.
+    ProxyHeader string
+    //TrustedProxies lista os IPs ou redes (CIDR) dos proxies cujo ProxyHeader é aceito. Em requisições de outros endereços
+    //o cabeçalho é ignorado, para que um cliente não escolha o próprio IP. É obrigatório quando ProxyHeader é informado.
+    TrustedProxies []string
+}
+
+//Modos aceitos em server.mode.
+const (
+    ModeProduction  = "production"
+    ModeDevelopment = "development"
+)
+
+//Production informa se o servidor está em modo de produção.
+func (s ServerConfig) Production() bool {
+    return s.Mode == ModeProduction
+}
+
+//AuthConfig aponta para os arquivos usados na autenticação e na autorização.
+type AuthConfig struct {
+    //JWTKeysFile é o arquivo JWKS com as chaves que verificam os tokens JWT. Obrigatório.
+    JWTKeysFile string
+    //PolicyFile é o arquivo YAML com os papéis e as regras de acesso.
+    PolicyFile string
+}
+
+//UsersConfig configura o ciclo de vida dos usuários.
+type UsersConfig struct {
+    //Retention é o tempo que um usuário excluído logicamente é mantido antes de poder ser removido definitivamente.
+    Retention time.Duration
+}
+
+//RateLimitConfig define os orçamentos de cada cliente: leituras (GET e HEAD) e escritas.
+//IP é o orçamento de cada endereço IP, consumido antes da autenticação por todas as requisições, inclusive as com credenciais inválidas.
+type RateLimitConfig struct {
+    Read  RateLimit
+    Write RateLimit
+    IP    RateLimit
+    //Timeout limita cada consulta ao store do limitador. É menor que server.requestTimeout porque, se o store
+    //não responder, a requisição é permitida: esperar mais só atrasaria todas as respostas.
+    Timeout time.Duration
+}
+
+//RateLimit é um orçamento de requisições por janela de tempo, escrito como "requisições/janela" (ex.: "300/1m").
+type RateLimit struct {
+    Requests int64
+    Window   time.Duration
+}
+
+//IdempotencyConfig configura as respostas guardadas de requisições com Idempotency-Key.
+type IdempotencyConfig struct {
+    //TTL é o tempo durante o qual a resposta de uma requisição com Idempotency-Key é guardada.
+    TTL time.Duration
+}
+
+//TracingConfig configura o envio dos traces do OpenTelemetry.
+type TracingConfig struct {
+    //Exporter é "otlp", "stdout" ou "none". Com "otlp", o destino vem das variáveis padrão do OpenTelemetry
+    //(ex.: OTEL_EXPORTER_OTLP_ENDPOINT).
+    Exporter string
+    //ServiceName identifica a aplicação nos traces (atributo service.name).
+    ServiceName string
+}
+
+//LogConfig configura os logs da aplicação.
+type LogConfig struct {
+    //Level é o nível mínimo dos logs: "debug", "info", "warn" ou "error".
+    Level string
+    //Format é "json" (uma linha JSON por registro) ou "text" (chave=valor).
+    Format string
+}
+
+//Default retorna a configuração usada quando nenhuma fonte informa um valor.
+//Mongo.URI e Auth.JWTKeysFile não têm padrão e precisam ser informados.
+func Default() Config {
+    return Config{
+        Mongo:       MongoConfig{Database: "golangAPI", ConnectTimeout: 10 * time.Second},
+        Server:      ServerConfig{Addr: ":6000", RequestTimeout: 10 * time.Second, BulkTimeout: 30 * time.Second, ImportTimeout: 60 * time.Second, ShutdownTimeout: 15 * time.Second, ReadinessTimeout: 2 * time.Second, Mode: ModeProduction},
+        Auth:        AuthConfig{PolicyFile: "configs/policy.yaml"},
+        Users:       UsersConfig{Retention: 30 * 24 * time.Hour},
+        RateLimit:   RateLimitConfig{Read: RateLimit{Requests: 300, Window: time.Minute}, Write: RateLimit{Requests: 60, Window: time.Minute}, IP: RateLimit{Requests: 600, Window: time.Minute}, Timeout: 2 * time.Second},
+        Idempotency: IdempotencyConfig{TTL: 24 * time.Hour},
+        Tracing:     TracingConfig{Exporter: tracing.ExporterNone, ServiceName: "golang-mongodb-api"},
+        Log:         LogConfig{Level: "info", Format: logging.FormatJSON},
+    }
+}
+
+//Um campo da configuração e os nomes pelos quais ele pode ser informado em cada fonte.
+type field struct {
+    //key é o nome no arquivo de configuração, no formato "seção.campo".
+    key   string
+    env   string
+    flag  string
+    usage string
+    value flag.Value
+    //secret faz Print ocultar o valor.
+    secret bool
+    //allowZero aceita zero em durações, que normalmente precisam ser positivas.
+    allowZero bool
+    //optional aceita textos vazios, que normalmente são obrigatórios.
+    optional bool
+}
+
+//Lista os campos da configuração, na ordem em que aparecem em Print e no --help.
+//Os valores apontam para os campos de c, então Set altera a própria configuração.
+func (c *Config) fields() []field {
+    return []field{
+        {key: "mongo.uri", env: "MONGOURI", flag: "mongo-uri", usage: "MongoDB connection URI", value: stringValue{&c.Mongo.URI}, secret: true},
+        {key: "mongo.database", env: "MONGO_DATABASE", flag: "mongo-database", usage: "MongoDB database name", value: stringValue{&c.Mongo.Database}},
+        {key: "mongo.connectTimeout", env: "MONGO_CONNECT_TIMEOUT", flag: "mongo-connect-timeout", usage: "timeout for connecting to, creating indexes in and disconnecting from MongoDB", value: durationValue{&c.Mongo.ConnectTimeout}},
+        {key: "server.addr", env: "SERVER_ADDR", flag: "addr", usage: "HTTP listen address", value: stringValue{&c.Server.Addr}},
+        {key: "server.requestTimeout", env: "REQUEST_TIMEOUT", flag: "request-timeout", usage: "timeout for the database operations of a request", value: durationValue{&c.Server.RequestTimeout}},
+        {key: "server.bulkTimeout", env: "BULK_TIMEOUT", flag: "bulk-timeout", usage: "timeout for POST /users/bulk", value: durationValue{&c.Server.BulkTimeout}},
+        {key: "server.importTimeout", env: "IMPORT_TIMEOUT", flag: "import-timeout", usage: "timeout for POST /users/import", value: durationValue{&c.Server.ImportTimeout}},
+        {key: "server.shutdownTimeout", env: "SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "how long to wait for in-flight requests on shutdown", value: durationValue{&c.Server.ShutdownTimeout}},
+        {key: "server.drainDelay", env: "DRAIN_DELAY", flag: "drain-delay", usage: "how long to keep serving after /readyz reports not ready on shutdown", value: durationValue{&c.Server.DrainDelay}, allowZero: true},
+        {key: "server.readinessTimeout", env: "READINESS_TIMEOUT", flag: "readiness-timeout", usage: "timeout for each dependency check of /readyz", value: durationValue{&c.Server.ReadinessTimeout}},
+        {key: "server.mode", env: "SERVER_MODE", flag: "mode", usage: "production hides internal error details from responses; development shows them", value: stringValue{&c.Server.Mode}},
+        {key: "server.proxyHeader", env: "PROXY_HEADER", flag: "proxy-header", usage: "header with the client IP set by the trusted proxies, such as X-Real-IP; empty uses the connection address", value: stringValue{&c.Server.ProxyHeader}, optional: true},
+        {key: "server.trustedProxies", env: "TRUSTED_PROXIES", flag: "trusted-proxies", usage: "comma-separated IPs or CIDR ranges of the proxies allowed to set the proxy header", value: listValue{&c.Server.TrustedProxies}},
+        {key: "auth.jwtKeysFile", env: "JWT_KEYS_FILE", flag: "jwt-keys-file", usage: "JWKS file with the keys that verify JWTs", value: stringValue{&c.Auth.JWTKeysFile}},
+        {key: "auth.policyFile", env: "POLICY_FILE", flag: "policy-file", usage: "YAML file with the access policies", value: stringValue{&c.Auth.PolicyFile}},
+        {key: "users.retention", env: "USER_RETENTION", flag: "user-retention", usage: "how long soft-deleted users are kept before they can be purged", value: durationValue{&c.Users.Retention}, allowZero: true},
+        {key: "rateLimit.read", env: "RATE_LIMIT_READ", flag: "rate-limit-read", usage: "read budget per client, as requests/window", value: &c.RateLimit.Read},
+        {key: "rateLimit.write", env: "RATE_LIMIT_WRITE", flag: "rate-limit-write", usage: "write budget per client, as requests/window", value: &c.RateLimit.Write},
+        {key: "rateLimit.ip", env: "RATE_LIMIT_IP", flag: "rate-limit-ip", usage: "budget per IP address, checked before authentication, as requests/window", value: &c.RateLimit.IP},
+        {key: "rateLimit.timeout", env: "RATE_LIMIT_TIMEOUT", flag: "rate-limit-timeout", usage: "timeout for each rate limit store lookup; requests are allowed when it expires", value: durationValue{&c.RateLimit.Timeout}},
+        {key: "idempotency.ttl", env: "IDEMPOTENCY_TTL", flag: "idempotency-ttl", usage: "how long responses to requests with Idempotency-Key are kept", value: durationValue{&c.Idempotency.TTL}},
+        {key: "tracing.exporter", env: "TRACING_EXPORTER", flag: "tracing-exporter", usage: "trace exporter: otlp, stdout or none", value: stringValue{&c.Tracing.Exporter}},
+        {key: "tracing.serviceName", env: "OTEL_SERVICE_NAME", flag: "tracing-service-name", usage: "service name reported in traces", value: stringValue{&c.Tracing.ServiceName}},
+        {key: "log.level", env: "LOG_LEVEL", flag: "log-level", usage: "minimum log level: debug, info, warn or error", value: stringValue{&c.Log.Level}},
+        {key: "log.format", env: "LOG_FORMAT", flag: "log-format", usage: "log format: json or text", value: stringValue{&c.Log.Format}},
+    }
+}
+
+//Load carrega a configuração a partir dos valores padrão, do arquivo de configuração, das variáveis de ambiente
+//e dos argumentos da linha de comando (normalmente os.Args[1:]), e valida o resultado.
+//O arquivo é informado por --config ou pela variável CONFIG_FILE; o formato (YAML ou TOML) vem da extensão.
+//Um arquivo .env no diretório atual é opcional: se existir, suas variáveis são carregadas sem sobrescrever as do sistema.
+//Todos os erros encontrados são retornados juntos, cada um indicando a fonte do valor inválido.
+func Load(args []string) (Config, error) {
+    if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return Config{}, fmt.Errorf("loading .env: %w", err)
+    }
+
+    config := Default()
+    fields := config.fields()
+
+    //As flags são só registradas durante o parse e aplicadas depois do arquivo e das variáveis de ambiente,
+    //já que o próprio arquivo é escolhido por uma flag.
+    flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+    configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file (env CONFIG_FILE)")
+    flags.BoolVar(&config.PrintConfig, "print-config", false, "print the configuration with secrets redacted and exit")
+    flagValues := map[string]string{}
+    for _, f := range fields {
+        usage := fmt.Sprintf("%s (env %s, config %s", f.usage, f.env, f.key)
+        if value := f.value.String(); value != "" && !f.secret {
+            usage += ", default " + value
+        }
+        flags.Func(f.flag, usage+")", func(value string) error {
+            flagValues[f.flag] = value
+            return nil
+        })
+    }
+    if err := flags.Parse(args); err != nil {
+        return Config{}, err
+    }
+    if flags.NArg() > 0 {
+        return Config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+    }
+
+    var errs []error
+    set := func(f field, source, value string) {
+        if err := f.value.Set(value); err != nil {
+            errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", source, value, err))
+        }
+    }
+
+    if *configFile != "" {
+        values, err := readConfigFile(*configFile)
+        if err != nil {
+            return Config{}, err
+        }
+        for _, f := range fields {
+            if value, ok := values[f.key]; ok {
+                set(f, *configFile+": "+f.key, value)
+                delete(values, f.key)
+            }
+        }
+        //Chaves que não correspondem a nenhum campo costumam ser erros de digitação, então não são ignoradas.
+        unknown := make([]string, 0, len(values))
+        for key := range values {
+            unknown = append(unknown, key)
+        }
+        sort.Strings(unknown)
+        for _, key := range unknown {
+            errs = append(errs, fmt.Errorf("%s: unknown key %q", *configFile, key))
+        }
+    }
+    for _, f := range fields {
+        if value := os.Getenv(f.env); value != "" {
+            set(f, "env "+f.env, value)
+        }
+    }
+    for _, f := range fields {
+        if value, ok := flagValues[f.flag]; ok {
+            set(f, "flag --"+f.flag, value)
+        }
+    }
+
+    errs = append(errs, config.validate()...)
+    if len(errs) > 0 {
+        return Config{}, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+    }
+    return config, nil
+}
+
+//Confere as regras que não dependem de um único valor ser bem formado: campos obrigatórios e limites.
+func (c *Config) validate() []error {
+    var errs []error
+    for _, f := range c.fields() {
+        required := func(ok bool, rule string) {
+            if !ok {
+                errs = append(errs, fmt.Errorf("%s %s (set %s, --%s or %s in the config file)", f.key, rule, f.env, f.flag, f.key))
+            }
+        }
+        switch value := f.value.(type) {
+        case stringValue:
+            //--print-config serve justamente para descobrir o que falta, então os campos obrigatórios não são exigidos.
+            required(f.optional || c.PrintConfig || *value.p != "", "is required")
+        case durationValue:
+            if f.allowZero {
+                required(*value.p >= 0, "must not be negative")
+            } else {
+                required(*value.p > 0, "must be positive")
+            }
+        }
+    }
+    switch c.Server.Mode {
+    case ModeProduction, ModeDevelopment, "":
+    default:
+        errs = append(errs, fmt.Errorf("server.mode must be %s or %s", ModeProduction, ModeDevelopment))
+    }
+    if c.Server.ProxyHeader != "" && len(c.Server.TrustedProxies) == 0 {
+        errs = append(errs, errors.New("server.trustedProxies is required when server.proxyHeader is set"))
+    }
+    for _, proxy := range c.Server.TrustedProxies {
+        if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+            errs = append(errs, fmt.Errorf("server.trustedProxies: %q is not an IP address or CIDR range", proxy))
+        }
+    }
+    switch c.Tracing.Exporter {
+    case tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterNone:
+    default:
+        errs = append(errs, fmt.Errorf("tracing.exporter must be %s, %s or %s", tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterNone))
+    }
+    if _, err := logging.ParseLevel(c.Log.Level); c.Log.Level != "" && err != nil {
+        errs = append(errs, errors.New("log.level must be debug, info, warn or error"))
+    }
+    switch c.Log.Format {
+    case logging.FormatJSON, logging.FormatText, "":
+    default:
+        errs = append(errs, fmt.Errorf("log.format must be %s or %s", logging.FormatJSON, logging.FormatText))
+    }
+    if c.Mongo.URI != "" {
+        if uri, err := url.Parse(c.Mongo.URI); err != nil || (uri.Scheme != "mongodb" && uri.Scheme != "mongodb+srv") {
+            errs = append(errs, errors.New("mongo.uri must be a mongodb:// or mongodb+srv:// URI"))
+        }
+    }
+    return errs
+}
+
+//Lê o arquivo de configuração e retorna seus valores indexados por "seção.campo".
+func readConfigFile(path string) (map[string]string, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading config file: %w", err)
+    }
+
+    var document map[string]any
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, &document)
+    case ".toml":
+        err = toml.Unmarshal(data, &document)
+    default:
+        return nil, fmt.Errorf("config file %s must have a .yaml, .yml or .toml extension", path)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+    }
+
+    values := map[string]string{}
+    for section, raw := range document {
+        fields, ok := raw.(map[string]any)
+        if !ok {
+            return nil, fmt.Errorf("%s: %q must be a section with fields", path, section)
+        }
+        for name, value := range fields {
+            switch value.(type) {
+            case map[string]any, []any:
+                return nil, fmt.Errorf("%s: %s.%s must be a single value", path, section, name)
+            }
+            values[section+"."+name] = fmt.Sprint(value)
+        }
+    }
+    return values, nil
+}
+
+//Print escreve a configuração em YAML, no mesmo formato aceito pelo arquivo de configuração.
+//Valores secretos são ocultados: na URI do MongoDB, apenas a senha é substituída.
+func (c Config) Print(w io.Writer) error {
+    document := &yaml.Node{Kind: yaml.MappingNode}
+    var section *yaml.Node
+    for _, f := range c.fields() {
+        sectionName, name, _ := strings.Cut(f.key, ".")
+        if section == nil || document.Content[len(document.Content)-2].Value != sectionName {
+            section = &yaml.Node{Kind: yaml.MappingNode}
+            document.Content = append(document.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: sectionName}, section)
+        }
+        value := f.value.String()
+        if f.secret {
+            value = redact(value)
+        }
+        section.Content = append(section.Content,
+            &yaml.Node{Kind: yaml.ScalarNode, Value: name},
+            &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+    }
+
+    encoder := yaml.NewEncoder(w)
+    encoder.SetIndent(2)
+    if err := encoder.Encode(document); err != nil {
+        return err
+    }
+    return encoder.Close()
+}
+
+//Oculta um valor secreto. Em URIs com senha, mantém o restante (host, banco e opções), que ajuda a conferir a configuração.
+func redact(value string) string {
+    if value == "" {
+        return ""
+    }
+    if uri, err := url.Parse(value); err == nil && uri.User != nil {
+        if _, ok := uri.User.Password(); ok {
+            uri.User = url.UserPassword(uri.User.Username(), "REDACTED")
+            return uri.String()
+        }
+    }
+    return "REDACTED"
+}
+
+//Implementações de flag.Value que escrevem diretamente nos campos de Config.
+type stringValue struct{ p *string }
+
+func (v stringValue) Set(value string) error {
+    *v.p = value
+    return nil
+}
+
+func (v stringValue) String() string { return *v.p }
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) Set(value string) error {
+    duration, err := time.ParseDuration(value)
+    if err != nil {
+        return errors.New("expected a duration such as 30s, 10m or 24h")
+    }
+    *v.p = duration
+    return nil
+}
+
+func (v durationValue) String() string { return v.p.String() }
+
+//Lista separada por vírgulas (ex.: "10.0.0.0/8,192.168.1.10"). Espaços em volta dos itens e itens vazios são ignorados.
+type listValue struct{ p *[]string }
+
+func (v listValue) Set(value string) error {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    *v.p = items
+    return nil
+}
+
+func (v listValue) String() string { return strings.Join(*v.p, ",") }
+
+//Set lê o orçamento no formato "requisições/janela" (ex.: "300/1m"). Os dois valores precisam ser positivos.
+func (r *RateLimit) Set(value string) error {
+    rawRequests, rawWindow, found := strings.Cut(value, "/")
+    requests, err := strconv.ParseInt(rawRequests, 10, 64)
+    window, windowErr := time.ParseDuration(rawWindow)
+    if !found || err != nil || windowErr != nil || requests <= 0 || window <= 0 {
+        return errors.New("expected requests/window such as 300/1m")
+    }
+    r.Requests, r.Window = requests, window
+    return nil
+}
+
+func (r *RateLimit) String() string {
+    return strconv.FormatInt(r.Requests, 10) + "/" + r.Window.String()
+}
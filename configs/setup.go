@@ -3,57 +3,57 @@ package configs
 import (
     "context" // Permite controlar o tempo limite (timeout) e o cancelamento em chamadas de funções.
     "fmt"
-    "log"
-    "time"
+    "log/slog"
     "go.mongodb.org/mongo-driver/mongo"
     "go.mongodb.org/mongo-driver/mongo/options" //Parte do driver oficial do MongoDB para Go, usado para conexão e configuração.
 )
 
 //retorna um ponteiro para uma instância de mongo.Client. Essa função é responsável por configurar e estabelecer a conexão com o MongoDB.
-func ConnectDB() *mongo.Client  {
-	//Cria um novo cliente MongoDB usando mongo.NewClient e define a URI de conexão com ApplyURI, que é fornecida pela função EnvMongoURI.
-    client, err := mongo.NewClient(options.Client().ApplyURI(EnvMongoURI()))
+//Quem chama é dono do cliente e deve encerrá-lo com Disconnect (ver App em main).
+//opts são aplicadas depois da URI, por exemplo para registrar os monitores de comandos e do pool de conexões usados nas métricas.
+func ConnectDB(config MongoConfig, opts ...*options.ClientOptions) (*mongo.Client, error) {
+	//Cria um novo cliente MongoDB usando mongo.NewClient e define a URI de conexão com ApplyURI, que vem da configuração (mongo.uri).
+    client, err := mongo.NewClient(append([]*options.ClientOptions{options.Client().ApplyURI(config.URI)}, opts...)...)
 
-	//Se ocorrer um erro durante a criação do cliente, o programa exibe o erro e encerra a execução com log.Fatal.
+	//Se ocorrer um erro durante a criação do cliente (ex.: URI inválida), o erro é retornado.
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
 
-	//Cria um contexto com um tempo limite de 10 segundos. Esse contexto é usado para controlar operações de conexão.
-    ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+	//Cria um contexto com o tempo limite de conexão configurado (mongo.connectTimeout). Esse contexto é usado para controlar operações de conexão.
+    ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+    defer cancel()
 	//Conecta ao MongoDB usando o cliente e o contexto configurado.
     err = client.Connect(ctx)
 
-	//caso ocorra um erro na conexão, ele é registrado e o programa é encerrado com log.Fatal.
+	//caso ocorra um erro na conexão, ele é retornado.
     if err != nil {
-        log.Fatal(err)
+        return nil, err
     }
 
     //Envia um comando ping ao MongoDB para verificar se a conexão está ativa.
     err = client.Ping(ctx, nil)
-	//Se o ping falhar, o programa encerra com o erro.
+	//Se o ping falhar, o cliente é desconectado (para liberar o pool de conexões) e o erro é retornado.
     if err != nil {
-        log.Fatal(err)
+        client.Disconnect(ctx)
+        return nil, fmt.Errorf("ping MongoDB: %w", err)
     }
-	//Caso contrário, exibe a mensagem "Connected to MongoDB" no console.
-    fmt.Println("Connected to MongoDB")
+	//Caso contrário, registra no log a conexão com o MongoDB.
+    slog.Info("connected to MongoDB", "database", config.Database)
 
 	//Retorna a instância do cliente conectado ao MongoDB.
-    return client
+    return client, nil
 }
 
-//Cria uma variável global chamada DB que armazena o cliente retornado pela função ConnectDB
-var DB *mongo.Client = ConnectDB()
-
 //retorna uma coleção (mongo.Collection) de um banco de dados.
-func GetCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	//Especifica o banco de dados chamado golangAPI
+func GetCollection(client *mongo.Client, database string, collectionName string) *mongo.Collection {
+	//Especifica o banco de dados configurado (mongo.database, golangAPI por padrão)
 	//Obtém uma coleção específica pelo nome fornecido no parâmetro collectionName.
-    collection := client.Database("golangAPI").Collection(collectionName)
+    collection := client.Database(database).Collection(collectionName)
     return collection
 }
 
-//Configura uma conexão com o MongoDB utilizando uma URI obtida via EnvMongoURI.
+//Configura uma conexão com o MongoDB utilizando a URI da configuração (MongoConfig).
 //Implementa um timeout para operações de conexão.
 //Faz um teste de conectividade com o MongoDB usando Ping.
 //Expõe a função GetCollection para obter coleções específicas de um banco de dados.
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/responses"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//APIKeyLocalsKey é a chave de c.Locals onde o middleware de chaves de API guarda a models.APIKey que autenticou a requisição.
+//Fica vazia em requisições autenticadas de outra forma (ex.: JWT).
+const APIKeyLocalsKey = "apiKey"
+
+//APIKeyController concentra os handlers administrativos de chaves de API.
+type APIKeyController struct {
+	store   stores.APIKeyStore
+	timeout time.Duration
+}
+
+//NewAPIKeyController cria um APIKeyController que grava as chaves no store informado.
+//timeout limita as operações de cada handler no store.
+func NewAPIKeyController(store stores.APIKeyStore, timeout time.Duration) *APIKeyController {
+	return &APIKeyController{store: store, timeout: timeout}
+}
+
+//Corpo de POST /admin/api-keys.
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write users:delete apikeys:admin"`
+	//ExpiresAt é opcional; sem ele, a chave vale até ser revogada.
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+//Define uma função que cria uma nova chave de API.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (kc *APIKeyController) CreateAPIKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), kc.timeout)
+	defer cancel()
+
+	//Converte e valida o corpo. Scopes desconhecidos ou uma validade no passado recebem 400 - Bad Request.
+	var request createAPIKeyRequest
+	if err := parseBody(c, &request); err != nil {
+		return problems.BadRequest(err.Error())
+	}
+	if validationErr := validateBody(c, &request); validationErr != nil {
+		return validationProblem(c, validationErr)
+	}
+	now := time.Now()
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(now) {
+		return problems.Validation("expiresAt must be in the future")
+	}
+
+	//Uma chave de API só pode criar chaves com scopes que ela mesma tem, para não escalar privilégios.
+	if caller, ok := c.Locals(APIKeyLocalsKey).(models.APIKey); ok {
+		for _, scope := range request.Scopes {
+			if !caller.HasScope(scope) {
+				return problems.Forbidden("API key cannot grant scope " + scope)
+			}
+		}
+	}
+
+	//Gera o segredo e guarda apenas o hash dele.
+	secret, prefix, err := stores.NewAPIKeySecret()
+	if err != nil {
+		return storeError(err)
+	}
+	key := models.APIKey{
+		Id:        primitive.NewObjectID(),
+		Name:      request.Name,
+		Prefix:    prefix,
+		Hash:      stores.HashAPIKey(secret),
+		Scopes:    request.Scopes,
+		CreatedBy: requestActor(c),
+		CreatedAt: now,
+		ExpiresAt: request.ExpiresAt,
+	}
+	if err := kc.store.Create(ctx, key); err != nil {
+		return storeError(err)
+	}
+
+	//Retorna 201 - Created com a chave completa. Ela não é guardada e não pode ser consultada depois.
+	return c.Status(http.StatusCreated).JSON(responses.UserResponse{Status: http.StatusCreated, Message: "success", Data: &fiber.Map{"data": key, "key": secret}})
+
+	//Validação: Nome, scopes conhecidos e validade futura.
+	//Privilégios: Chamadas feitas com chave de API só concedem scopes que a própria chave tem.
+	//Criação: O segredo é gerado aleatoriamente e apenas o hash SHA-256 é gravado.
+	//Resposta final: A chave completa é retornada uma única vez, junto com os metadados.
+}
+
+//Define uma função que lista as chaves de API.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (kc *APIKeyController) GetAllAPIKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), kc.timeout)
+	defer cancel()
+
+	//Os hashes nunca são serializados (json:"-"); a listagem mostra só o prefixo de cada chave.
+	keys, err := kc.store.List(ctx)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": keys}})
+}
+
+//Define uma função que revoga uma chave de API.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (kc *APIKeyController) RevokeAPIKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), kc.timeout)
+	defer cancel()
+
+	objId, err := primitive.ObjectIDFromHex(c.Params("keyId"))
+	if err != nil {
+		return problems.BadRequest("Invalid API key ID!")
+	}
+
+	//A chave não é apagada: revokedAt fica registrado e o middleware passa a recusá-la com 401.
+	key, err := kc.store.Revoke(ctx, objId, time.Now())
+	if err != nil {
+		return storeError(err)
+	}
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": key}})
+}
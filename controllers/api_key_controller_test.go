@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Monta um servidor com os handlers de /admin/api-keys sobre um MemoryAPIKeyStore.
+//O cabeçalho X-Test-Scopes simula uma requisição autenticada por uma chave de API com esses scopes (separados por vírgula),
+//no lugar do middleware de chaves; sem ele, a requisição é tratada como autenticada por JWT.
+func newTestAPIKeyApp(t *testing.T) (*fiber.App, *stores.MemoryAPIKeyStore) {
+	t.Helper()
+	store := stores.NewMemoryAPIKeyStore()
+	kc := NewAPIKeyController(store, time.Second)
+
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Use(func(c *fiber.Ctx) error {
+		if scopes := c.Get("X-Test-Scopes"); scopes != "" {
+			c.Locals(APIKeyLocalsKey, models.APIKey{Id: primitive.NewObjectID(), Scopes: strings.Split(scopes, ",")})
+			c.Locals(ActorLocalsKey, "apikey:caller")
+		}
+		return c.Next()
+	})
+	app.Post("/admin/api-keys", kc.CreateAPIKey)
+	app.Get("/admin/api-keys", kc.GetAllAPIKeys)
+	app.Delete("/admin/api-keys/:keyId", kc.RevokeAPIKey)
+	return app, store
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	app, store := newTestAPIKeyApp(t)
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	resp := doRequest(t, app, "POST", "/admin/api-keys", `{"name":"nightly-sync","scopes":["users:read","users:write"],"expiresAt":"`+expiresAt+`"}`).
+		expect(t, http.StatusCreated)
+	envelope := resp.body["data"].(map[string]interface{})
+	secret, _ := envelope["key"].(string)
+	created := envelope["data"].(map[string]interface{})
+	if !strings.HasPrefix(secret, "uak_") || !strings.HasPrefix(secret, created["prefix"].(string)) {
+		t.Errorf("key = %q, prefix = %v, want a uak_ key starting with the prefix", secret, created["prefix"])
+	}
+	if _, ok := created["hash"]; ok {
+		t.Errorf("response exposes the key hash: %s", resp.raw)
+	}
+	if created["name"] != "nightly-sync" || created["createdBy"] != "anonymous" || created["expiresAt"] != expiresAt {
+		t.Errorf("created key = %v", created)
+	}
+
+	//Só o hash é gravado, e ele encontra a chave pelo segredo devolvido.
+	stored, err := store.GetByHash(context.Background(), stores.HashAPIKey(secret))
+	if err != nil {
+		t.Fatalf("GetByHash of the returned key: %v", err)
+	}
+	if stored.Id.Hex() != created["id"] || stored.Hash == secret || !stored.HasScope(models.ScopeUsersWrite) {
+		t.Errorf("stored key = %+v", stored)
+	}
+}
+
+func TestCreateAPIKeyRejectsInvalidRequests(t *testing.T) {
+	app, _ := newTestAPIKeyApp(t)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := map[string]struct {
+		body        string
+		problemType string
+	}{
+		"malformed body":  {`{"name":`, problems.TypeBadRequest},
+		"missing name":    {`{"scopes":["users:read"]}`, problems.TypeValidation},
+		"no scopes":       {`{"name":"ci","scopes":[]}`, problems.TypeValidation},
+		"unknown scope":   {`{"name":"ci","scopes":["users:admin"]}`, problems.TypeValidation},
+		"expired already": {`{"name":"ci","scopes":["users:read"],"expiresAt":"` + past + `"}`, problems.TypeValidation},
+	}
+	for name, test := range tests {
+		resp := doRequest(t, app, "POST", "/admin/api-keys", test.body)
+		if resp.status != http.StatusBadRequest || resp.body["type"] != test.problemType {
+			t.Errorf("%s: status = %d, type = %v, want 400 %s", name, resp.status, resp.body["type"], test.problemType)
+		}
+	}
+}
+
+func TestCreateAPIKeyScopeEscalation(t *testing.T) {
+	app, store := newTestAPIKeyApp(t)
+	caller := models.ScopeAPIKeysAdmin + "," + models.ScopeUsersRead
+
+	//Uma chave de API não concede scopes que ela mesma não tem.
+	doRequest(t, app, "POST", "/admin/api-keys", `{"name":"ci","scopes":["users:read","users:delete"]}`, "X-Test-Scopes", caller).
+		expectProblem(t, http.StatusForbidden, problems.TypeForbidden)
+	if keys, _ := store.List(context.Background()); len(keys) != 0 {
+		t.Errorf("got %d keys after a forbidden create, want 0", len(keys))
+	}
+
+	created := doRequest(t, app, "POST", "/admin/api-keys", `{"name":"ci","scopes":["users:read"]}`, "X-Test-Scopes", caller).
+		expect(t, http.StatusCreated).body["data"].(map[string]interface{})["data"].(map[string]interface{})
+	if created["createdBy"] != "apikey:caller" {
+		t.Errorf("createdBy = %v, want the calling key", created["createdBy"])
+	}
+	//Requisições autenticadas por JWT não têm essa restrição.
+	doRequest(t, app, "POST", "/admin/api-keys", `{"name":"ci","scopes":["users:delete","apikeys:admin"]}`).expect(t, http.StatusCreated)
+}
+
+func TestListAndRevokeAPIKeys(t *testing.T) {
+	app, store := newTestAPIKeyApp(t)
+	var ids []string
+	for _, name := range []string{"first", "second"} {
+		created := doRequest(t, app, "POST", "/admin/api-keys", `{"name":"`+name+`","scopes":["users:read"]}`).
+			expect(t, http.StatusCreated).body["data"].(map[string]interface{})["data"].(map[string]interface{})
+		ids = append(ids, created["id"].(string))
+	}
+
+	list := func() []map[string]interface{} {
+		t.Helper()
+		var keys []map[string]interface{}
+		for _, key := range doRequest(t, app, "GET", "/admin/api-keys", "").expect(t, http.StatusOK).data(t).([]interface{}) {
+			keys = append(keys, key.(map[string]interface{}))
+		}
+		return keys
+	}
+	//Das mais novas para as mais antigas, sem o hash.
+	keys := list()
+	if len(keys) != 2 || keys[0]["name"] != "second" || keys[1]["name"] != "first" {
+		t.Fatalf("GET /admin/api-keys = %v, want second then first", keys)
+	}
+	for _, key := range keys {
+		if _, ok := key["hash"]; ok {
+			t.Errorf("listed key exposes the hash: %v", key)
+		}
+	}
+
+	revoked := doRequest(t, app, "DELETE", "/admin/api-keys/"+ids[0], "").expect(t, http.StatusOK).data(t).(map[string]interface{})
+	revokedAt, _ := revoked["revokedAt"].(string)
+	if revokedAt == "" {
+		t.Fatalf("revoked key = %v, want revokedAt", revoked)
+	}
+	//Revogar de novo mantém a data original.
+	again := doRequest(t, app, "DELETE", "/admin/api-keys/"+ids[0], "").expect(t, http.StatusOK).data(t).(map[string]interface{})
+	if again["revokedAt"] != revokedAt {
+		t.Errorf("revokedAt changed from %s to %v", revokedAt, again["revokedAt"])
+	}
+	//A chave revogada continua na listagem; a outra não é afetada.
+	keys = list()
+	if len(keys) != 2 || keys[1]["revokedAt"] != revokedAt || keys[0]["revokedAt"] != nil {
+		t.Errorf("GET /admin/api-keys after revoke = %v", keys)
+	}
+	if stored, _ := store.List(context.Background()); stored[1].RevokedAt == nil {
+		t.Errorf("stored key %s was not revoked", ids[0])
+	}
+
+	doRequest(t, app, "DELETE", "/admin/api-keys/"+primitive.NewObjectID().Hex(), "").expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+	doRequest(t, app, "DELETE", "/admin/api-keys/not-an-id", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/responses"
+)
+
+//Dependency é um serviço externo conferido por GET /readyz (ex.: o MongoDB).
+type Dependency struct {
+	Name string
+	//Check retorna nil quando a dependência responde. Deve respeitar o prazo de ctx.
+	Check func(ctx context.Context) error
+}
+
+//HealthController concentra os handlers de liveness e readiness usados pelo orquestrador.
+//A aplicação começa "não pronta" e só passa a pronta com SetReady(true), depois que o servidor está escutando;
+//no encerramento gracioso, SetReady(false) faz /readyz responder 503 enquanto as requisições em andamento terminam.
+type HealthController struct {
+	dependencies []Dependency
+	//Tempo limite de cada verificação de dependência.
+	timeout time.Duration
+	ready   atomic.Bool
+}
+
+//NewHealthController cria um HealthController que confere as dependências informadas, cada uma com o timeout informado.
+func NewHealthController(timeout time.Duration, dependencies ...Dependency) *HealthController {
+	return &HealthController{dependencies: dependencies, timeout: timeout}
+}
+
+//SetReady marca a aplicação como pronta (ou não) para receber tráfego.
+func (hc *HealthController) SetReady(ready bool) {
+	hc.ready.Store(ready)
+}
+
+//Resultado da verificação de uma dependência em GET /readyz.
+type dependencyStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	//Tempo da verificação em milissegundos.
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+//Define uma função que informa se o processo está vivo (liveness).
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (hc *HealthController) Liveness(c *fiber.Ctx) error {
+	//Não consulta as dependências: uma falha do MongoDB não deve fazer o orquestrador reiniciar o processo.
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": "alive"}})
+}
+
+//Define uma função que informa se a aplicação está pronta para receber tráfego (readiness).
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (hc *HealthController) Readiness(c *fiber.Ctx) error {
+	//Durante a inicialização e o encerramento, responde 503 sem consultar as dependências.
+	if !hc.ready.Load() {
+		return problems.Unavailable("not ready")
+	}
+
+	//Confere cada dependência com um tempo limite curto e mede a latência.
+	ready := true
+	dependencies := make([]dependencyStatus, 0, len(hc.dependencies))
+	for _, dependency := range hc.dependencies {
+		result := hc.check(c.UserContext(), dependency)
+		if result.Status != "up" {
+			ready = false
+		}
+		dependencies = append(dependencies, result)
+	}
+
+	//Retorna 200 - OK quando todas as dependências respondem e 503 - Service Unavailable (um problema com o membro
+	//dependencies) quando alguma falha, sempre com o status e a latência de cada uma.
+	if !ready {
+		return problems.Unavailable("not ready").With("dependencies", dependencies)
+	}
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": "ready", "dependencies": dependencies}})
+}
+
+//Executa a verificação de uma dependência com o timeout do controller.
+//O erro vai apenas para o log (com o contexto da requisição, requestCtx): /readyz não exige autenticação
+//e a mensagem pode conter endereços internos.
+func (hc *HealthController) check(requestCtx context.Context, dependency Dependency) dependencyStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), hc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dependency.Check(ctx)
+	result := dependencyStatus{Name: dependency.Name, Status: "up", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = "down"
+		slog.WarnContext(requestCtx, "readiness: dependency is down", "dependency", dependency.Name, "error", err)
+	}
+	return result
+}
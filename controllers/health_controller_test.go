@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Monta um servidor com GET /healthz e GET /readyz sobre as dependências informadas.
+func newTestHealthApp(t *testing.T, dependencies ...Dependency) (*fiber.App, *HealthController) {
+	t.Helper()
+	hc := NewHealthController(50*time.Millisecond, dependencies...)
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Get("/healthz", hc.Liveness)
+	app.Get("/readyz", hc.Readiness)
+	return app, hc
+}
+
+//Retorna o status de cada dependência do corpo de /readyz, indexado pelo nome.
+func dependencyStatuses(t *testing.T, dependencies interface{}) map[string]string {
+	t.Helper()
+	statuses := map[string]string{}
+	list, _ := dependencies.([]interface{})
+	for _, raw := range list {
+		dependency := raw.(map[string]interface{})
+		if _, ok := dependency["latencyMs"].(float64); !ok {
+			t.Errorf("dependency %v has no latencyMs", dependency)
+		}
+		statuses[dependency["name"].(string)] = dependency["status"].(string)
+	}
+	return statuses
+}
+
+func TestLiveness(t *testing.T) {
+	down := Dependency{Name: "mongo", Check: func(context.Context) error { return errors.New("connection refused") }}
+	app, hc := newTestHealthApp(t, down)
+
+	//GET /healthz não depende de SetReady nem das dependências.
+	for _, ready := range []bool{false, true} {
+		hc.SetReady(ready)
+		if data := doRequest(t, app, "GET", "/healthz", "").expect(t, http.StatusOK).data(t); data != "alive" {
+			t.Errorf("ready %v: GET /healthz data = %v, want alive", ready, data)
+		}
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	var mongoErr error
+	checks := 0
+	mongo := Dependency{Name: "mongo", Check: func(ctx context.Context) error {
+		checks++
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("dependency check has no deadline")
+		}
+		return mongoErr
+	}}
+	cache := Dependency{Name: "cache", Check: func(context.Context) error { return nil }}
+	app, hc := newTestHealthApp(t, mongo, cache)
+
+	//Antes de SetReady(true) (e durante o encerramento) responde 503 sem consultar as dependências.
+	doRequest(t, app, "GET", "/readyz", "").expectProblem(t, http.StatusServiceUnavailable, problems.TypeUnavailable)
+	if checks != 0 {
+		t.Errorf("dependencies checked %d times before ready, want 0", checks)
+	}
+
+	hc.SetReady(true)
+	resp := doRequest(t, app, "GET", "/readyz", "").expect(t, http.StatusOK)
+	envelope := resp.body["data"].(map[string]interface{})
+	if envelope["data"] != "ready" {
+		t.Errorf("GET /readyz data = %v, want ready", envelope["data"])
+	}
+	if statuses := dependencyStatuses(t, envelope["dependencies"]); statuses["mongo"] != "up" || statuses["cache"] != "up" {
+		t.Errorf("dependencies = %v, want both up", statuses)
+	}
+
+	//Uma dependência que falha deixa a aplicação não pronta; a causa não vai para a resposta.
+	mongoErr = errors.New("dial tcp 10.0.0.5:27017: connection refused")
+	resp = doRequest(t, app, "GET", "/readyz", "").expectProblem(t, http.StatusServiceUnavailable, problems.TypeUnavailable)
+	if statuses := dependencyStatuses(t, resp.body["dependencies"]); statuses["mongo"] != "down" || statuses["cache"] != "up" {
+		t.Errorf("dependencies = %v, want mongo down and cache up", statuses)
+	}
+	if detail := resp.body["detail"]; detail != "not ready" {
+		t.Errorf("detail = %v, want not ready without the cause", detail)
+	}
+
+	mongoErr = nil
+	doRequest(t, app, "GET", "/readyz", "").expect(t, http.StatusOK)
+	hc.SetReady(false)
+	doRequest(t, app, "GET", "/readyz", "").expectProblem(t, http.StatusServiceUnavailable, problems.TypeUnavailable)
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	//Uma dependência que não responde é dada como down quando o timeout do controller acaba.
+	slow := Dependency{Name: "mongo", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	app, hc := newTestHealthApp(t, slow)
+	hc.SetReady(true)
+
+	start := time.Now()
+	resp := doRequest(t, app, "GET", "/readyz", "").expectProblem(t, http.StatusServiceUnavailable, problems.TypeUnavailable)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GET /readyz took %v, want about the 50ms timeout", elapsed)
+	}
+	if statuses := dependencyStatuses(t, resp.body["dependencies"]); statuses["mongo"] != "down" {
+		t.Errorf("dependencies = %v, want mongo down", statuses)
+	}
+}
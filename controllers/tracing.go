@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Executa fn em um span filho do span da requisição (ver middleware.Tracing), para que o trace mostre quanto tempo
+//cada etapa do handler levou, além dos comandos do MongoDB. Sem o middleware, o span não é gravado.
+func traced(c *fiber.Ctx, name string, fn func() error) error {
+	parent := c.UserContext()
+	tracer := trace.SpanFromContext(parent).TracerProvider().Tracer(tracing.ScopeName)
+	_, span := tracer.Start(parent, name)
+	defer span.End()
+
+	err := fn()
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+//Converte o corpo da requisição em out (como c.BodyParser), em um span "parse body".
+func parseBody(c *fiber.Ctx, out interface{}) error {
+	return traced(c, "parse body", func() error { return c.BodyParser(out) })
+}
+
+//Valida value com o validate dos handlers, em um span "validate".
+func validateBody(c *fiber.Ctx, value interface{}) error {
+	return traced(c, "validate", func() error { return validate.Struct(value) })
+}
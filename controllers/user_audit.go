@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/responses"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//ActorLocalsKey é a chave de c.Locals onde os middlewares de autenticação guardam a identidade de quem faz a requisição.
+//Ela é registrada como actor na trilha de auditoria; sem autenticação, o actor é "anonymous".
+const ActorLocalsKey = "actor"
+
+//Número máximo de tentativas de uma escrita sem If-Match quando outra requisição altera o usuário entre a leitura e a gravação.
+const maxWriteAttempts = 3
+
+//Retorna a identidade de quem faz a requisição, para a trilha de auditoria.
+func requestActor(c *fiber.Ctx) string {
+	if actor, ok := c.Locals(ActorLocalsKey).(string); ok && actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+//Grava um registro na trilha de auditoria. before é nil na criação.
+//A alteração do usuário já foi feita quando este método é chamado, então uma falha ao gravar a auditoria
+//é registrada no log em vez de transformar a resposta em erro.
+func (uc *UserController) recordAudit(ctx context.Context, c *fiber.Ctx, operation string, before, after *models.User) {
+	entry := models.AuditEntry{
+		Id:        primitive.NewObjectID(),
+		Operation: operation,
+		Actor:     requestActor(c),
+		Timestamp: time.Now(),
+		Before:    before,
+		After:     after,
+	}
+	if after != nil {
+		entry.UserId = after.Id
+	} else if before != nil {
+		entry.UserId = before.Id
+	}
+	if err := uc.audit.Record(ctx, entry); err != nil {
+		slog.ErrorContext(ctx, "audit: failed to record entry", "operation", operation, "userId", entry.UserId.Hex(), "error", err)
+	}
+}
+
+//Lê o usuário e aplica write exigindo a versão lida, para que o estado "antes" registrado na auditoria
+//seja exatamente o estado que foi alterado.
+//Com If-Match (expectedVersion diferente de stores.AnyVersion), a versão lida precisa ser a esperada;
+//caso contrário retorna stores.ErrVersionConflict. Sem If-Match, a leitura e a escrita são repetidas
+//até maxWriteAttempts vezes se outra requisição alterar o usuário no meio do caminho.
+func (uc *UserController) writeUser(ctx context.Context, id primitive.ObjectID, expectedVersion int64, write func(before models.User) (models.User, error)) (before, after models.User, err error) {
+	for attempt := 1; ; attempt++ {
+		before, err = uc.store.Get(ctx, id, false)
+		if err != nil {
+			return models.User{}, models.User{}, err
+		}
+		if expectedVersion != stores.AnyVersion && before.Version != expectedVersion {
+			return models.User{}, models.User{}, stores.ErrVersionConflict
+		}
+		after, err = write(before)
+		if errors.Is(err, stores.ErrVersionConflict) && expectedVersion == stores.AnyVersion && attempt < maxWriteAttempts {
+			continue
+		}
+		return before, after, err
+	}
+}
+
+//Define uma função que retorna o histórico de alterações de um usuário.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) GetUserHistory(c *fiber.Ctx) error {
+	//Cria um contexto com o timeout de requisição configurado para a consulta.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
+	defer cancel()
+
+	//Converte o parâmetro userId da URL para um ObjectID. IDs inválidos recebem um 400 - Bad Request.
+	objId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return problems.BadRequest("Invalid user ID!")
+	}
+
+	//A paginação segue as mesmas regras de GET /users: limit com teto do servidor e cursor opaco.
+	limit, err := parseLimit(c)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Busca uma página do histórico, do registro mais recente para o mais antigo.
+	//O histórico continua disponível mesmo depois que o usuário é excluído ou removido definitivamente.
+	page, err := uc.audit.History(ctx, objId, stores.AuditQuery{Limit: limit, Cursor: c.Query("cursor")})
+	if err != nil {
+		return storeError(err)
+	}
+
+	//next_cursor é null na última página.
+	var nextCursor *string
+	if page.NextCursor != "" {
+		nextCursor = &page.NextCursor
+	}
+
+	//Retorna uma resposta HTTP com status 200 (OK) com os registros da página e o cursor da próxima página.
+	return c.Status(http.StatusOK).JSON(
+		responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": page.Entries, "next_cursor": nextCursor, "limit": limit}},
+	)
+}
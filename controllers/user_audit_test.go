@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Busca uma página do histórico e retorna os registros e o next_cursor.
+func historyPage(t *testing.T, resp testResponse) ([]map[string]interface{}, interface{}) {
+	t.Helper()
+	envelope := resp.body["data"].(map[string]interface{})
+	var entries []map[string]interface{}
+	for _, entry := range envelope["data"].([]interface{}) {
+		entries = append(entries, entry.(map[string]interface{}))
+	}
+	return entries, envelope["next_cursor"]
+}
+
+//Retorna o campo field do snapshot before ou after de um registro, ou nil se o snapshot não existir.
+func snapshotField(entry map[string]interface{}, snapshot, field string) interface{} {
+	user, ok := entry[snapshot].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return user[field]
+}
+
+func TestUserHistory(t *testing.T) {
+	app, _ := newTestUserApp(t)
+	id := doRequest(t, app, "POST", "/user", `{"name":"Ana","location":"Lisbon","title":"Engineer"}`).expect(t, http.StatusCreated).user(t)["id"].(string)
+	target := "/user/" + id
+	doRequest(t, app, "PUT", target, `{"name":"Ana","location":"Lisbon","title":"Manager"}`).expect(t, http.StatusOK)
+	doRequest(t, app, "DELETE", target, "").expect(t, http.StatusOK)
+	doRequest(t, app, "POST", target+"/restore", "").expect(t, http.StatusOK)
+
+	entries, next := historyPage(t, doRequest(t, app, "GET", target+"/history", "").expect(t, http.StatusOK))
+	if next != nil {
+		t.Errorf("next_cursor = %v, want null", next)
+	}
+	//Do registro mais recente para o mais antigo.
+	want := []struct {
+		operation     string
+		beforeTitle   interface{}
+		afterTitle    interface{}
+		beforeVersion interface{}
+		afterVersion  interface{}
+	}{
+		{models.AuditRestore, "Manager", "Manager", 3.0, 4.0},
+		{models.AuditDelete, "Manager", "Manager", 2.0, 3.0},
+		{models.AuditUpdate, "Engineer", "Manager", 1.0, 2.0},
+		{models.AuditCreate, nil, "Engineer", nil, 1.0},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		entry := entries[i]
+		if entry["operation"] != w.operation || entry["userId"] != id || entry["actor"] != "anonymous" {
+			t.Errorf("entry %d = %v, want %s of %s by anonymous", i, entry, w.operation, id)
+			continue
+		}
+		if got := snapshotField(entry, "before", "title"); got != w.beforeTitle {
+			t.Errorf("%s: before.title = %v, want %v", w.operation, got, w.beforeTitle)
+		}
+		if got := snapshotField(entry, "after", "title"); got != w.afterTitle {
+			t.Errorf("%s: after.title = %v, want %v", w.operation, got, w.afterTitle)
+		}
+		if got := snapshotField(entry, "before", "version"); got != w.beforeVersion {
+			t.Errorf("%s: before.version = %v, want %v", w.operation, got, w.beforeVersion)
+		}
+		if got := snapshotField(entry, "after", "version"); got != w.afterVersion {
+			t.Errorf("%s: after.version = %v, want %v", w.operation, got, w.afterVersion)
+		}
+	}
+	//A exclusão registra deletedAt só no estado depois dela.
+	if deleted := entries[1]; snapshotField(deleted, "before", "deletedAt") != nil || snapshotField(deleted, "after", "deletedAt") == nil {
+		t.Errorf("delete entry = %v, want deletedAt only after the deletion", deleted)
+	}
+
+	//Escritas rejeitadas não geram registros.
+	doRequest(t, app, "PUT", target, `{"name":"Ana"}`).expect(t, http.StatusBadRequest)
+	if entries, _ := historyPage(t, doRequest(t, app, "GET", target+"/history", "").expect(t, http.StatusOK)); len(entries) != len(want) {
+		t.Errorf("got %d entries after a rejected write, want %d", len(entries), len(want))
+	}
+}
+
+func TestUserHistoryPagination(t *testing.T) {
+	app, _ := newTestUserApp(t)
+	id := doRequest(t, app, "POST", "/user", `{"name":"Ana","location":"Lisbon","title":"Engineer"}`).expect(t, http.StatusCreated).user(t)["id"].(string)
+	target := "/user/" + id
+	for _, title := range []string{"Manager", "Director", "Engineer", "Manager"} {
+		doRequest(t, app, "PUT", target, `{"name":"Ana","location":"Lisbon","title":"`+title+`"}`).expect(t, http.StatusOK)
+	}
+
+	//Cinco registros em páginas de 2: a última página tem next_cursor null.
+	var versions []string
+	pages := 0
+	for cursor := ""; ; {
+		url := target + "/history?limit=2"
+		if cursor != "" {
+			url += "&cursor=" + cursor
+		}
+		resp := doRequest(t, app, "GET", url, "").expect(t, http.StatusOK)
+		pages++
+		if limit := resp.body["data"].(map[string]interface{})["limit"]; limit != 2.0 {
+			t.Errorf("limit = %v, want 2", limit)
+		}
+		entries, next := historyPage(t, resp)
+		if len(entries) > 2 {
+			t.Fatalf("page %d has %d entries, want at most 2", pages, len(entries))
+		}
+		for _, entry := range entries {
+			versions = append(versions, fmt.Sprint(snapshotField(entry, "after", "version")))
+		}
+		if next == nil {
+			break
+		}
+		cursor = next.(string)
+	}
+	if pages != 3 {
+		t.Errorf("got %d pages, want 3", pages)
+	}
+	if got := strings.Join(versions, ","); got != "5,4,3,2,1" {
+		t.Errorf("after.version across pages = %s, want 5,4,3,2,1", got)
+	}
+
+	doRequest(t, app, "GET", target+"/history?cursor=not-a-cursor", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+	doRequest(t, app, "GET", target+"/history?limit=0", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+	doRequest(t, app, "GET", "/user/not-an-id/history", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+	if entries, next := historyPage(t, doRequest(t, app, "GET", "/user/"+primitive.NewObjectID().Hex()+"/history", "").expect(t, http.StatusOK)); len(entries) != 0 || next != nil {
+		t.Errorf("history of an unknown user = %v, %v, want an empty page", entries, next)
+	}
+}
+
+func TestPurgeUsersAudit(t *testing.T) {
+	app, store := newTestUserApp(t)
+	deletedAt := time.Now().Add(-2 * time.Hour)
+	user, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: "Ana", Location: "Lisbon", Title: "Engineer", DeletedAt: &deletedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doRequest(t, app, "POST", "/admin/users/purge", "").expect(t, http.StatusOK)
+
+	//O histórico continua disponível depois da remoção definitiva, com o último estado do usuário.
+	entries, _ := historyPage(t, doRequest(t, app, "GET", "/user/"+user.Id.Hex()+"/history", "").expect(t, http.StatusOK))
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want the purge entry", len(entries))
+	}
+	purge := entries[0]
+	if purge["operation"] != models.AuditPurge || purge["after"] != nil || snapshotField(purge, "before", "name") != "Ana" || snapshotField(purge, "before", "deletedAt") == nil {
+		t.Errorf("purge entry = %v, want the deleted user as before and no after", purge)
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/responses"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Maior número de operações aceito em um único POST /users/bulk.
+const maxBulkOperations = 1000
+
+//Corpo de POST /users/bulk.
+//
+//	{"ordered": true, "operations": [
+//	  {"op": "create", "user": {"name": "...", "location": "...", "title": "..."}},
+//	  {"op": "update", "id": "...", "version": 3, "user": {"name": "...", "location": "...", "title": "..."}},
+//	  {"op": "delete", "id": "..."}
+//	]}
+type bulkRequest struct {
+	//Ordered é true por padrão: a primeira operação que falha interrompe as seguintes.
+	Ordered    *bool                  `json:"ordered"`
+	Operations []bulkOperationRequest `json:"operations"`
+}
+
+type bulkOperationRequest struct {
+	Op string `json:"op"`
+	//Id identifica o usuário em update e delete.
+	Id string `json:"id"`
+	//Version é opcional e funciona como o If-Match de PUT e DELETE.
+	Version *int64      `json:"version"`
+	User    models.User `json:"user"`
+}
+
+//Resultado de uma operação em lote, na mesma posição (index) do array operations da requisição.
+type bulkItemResponse struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	Status int          `json:"status"`
+	Id     string       `json:"id,omitempty"`
+	Data   *models.User `json:"data,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	//Errors lista os campos de user que não passaram na validação (ver FieldError).
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+//Define uma função que cria, edita e exclui vários usuários em uma única requisição.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) BulkUsers(c *fiber.Ctx) error {
+	//Cria um contexto com o tempo limite de lotes configurado, normalmente maior que o das operações individuais por causa do tamanho do lote.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Bulk)
+	defer cancel()
+
+	//Converte o corpo da requisição. Corpos inválidos, vazios ou grandes demais recebem 400 - Bad Request.
+	var request bulkRequest
+	if err := parseBody(c, &request); err != nil {
+		return problems.BadRequest(err.Error())
+	}
+	if len(request.Operations) == 0 || len(request.Operations) > maxBulkOperations {
+		return problems.Validation("operations must contain between 1 and " + strconv.Itoa(maxBulkOperations) + " items")
+	}
+	ordered := request.Ordered == nil || *request.Ordered
+
+	//Valida cada operação com o mesmo validate usado em CreateUser e EditAUser.
+	//Operações inválidas recebem 400 no seu índice e não são enviadas ao store;
+	//no modo ordenado, as operações depois da primeira inválida são puladas.
+	items := make([]bulkItemResponse, len(request.Operations))
+	var operations []stores.BulkOperation
+	var indexes []int
+	invalid := false
+	for i, opRequest := range request.Operations {
+		items[i] = bulkItemResponse{Index: i, Op: opRequest.Op, Id: opRequest.Id}
+		if invalid && ordered {
+			items[i].Status, items[i].Error = http.StatusFailedDependency, stores.ErrBulkSkipped.Error()
+			continue
+		}
+		operation, err := parseBulkOperation(opRequest)
+		if err != nil {
+			items[i].Status = http.StatusBadRequest
+			items[i].Error, items[i].Errors = itemErrors(c, err, "user.")
+			invalid = true
+			continue
+		}
+		operations = append(operations, operation)
+		indexes = append(indexes, i)
+	}
+
+	//Executa as operações válidas no store (BulkWrite no MongoDB).
+	results, err := uc.store.BulkWrite(ctx, operations, ordered)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Preenche o resultado de cada operação executada e registra as alterações na trilha de auditoria.
+	succeeded := 0
+	for j, result := range results {
+		item := &items[indexes[j]]
+		if result.Err != nil {
+			if errors.Is(result.Err, stores.ErrBulkSkipped) {
+				item.Status, item.Error = http.StatusFailedDependency, result.Err.Error()
+			} else {
+				item.Status, item.Error = storeErrorStatus(c, result.Err)
+			}
+			continue
+		}
+
+		succeeded++
+		item.Id = result.After.Id.Hex()
+		item.Data = result.After
+		switch operations[j].Op {
+		case stores.BulkCreate:
+			item.Status = http.StatusCreated
+			uc.recordAudit(ctx, c, models.AuditCreate, nil, result.After)
+		case stores.BulkUpdate:
+			item.Status = http.StatusOK
+			uc.recordAudit(ctx, c, models.AuditUpdate, result.Before, result.After)
+		case stores.BulkDelete:
+			item.Status = http.StatusOK
+			uc.recordAudit(ctx, c, models.AuditDelete, result.Before, result.After)
+		}
+	}
+
+	//Retorna 200 - OK com um resultado por operação, mesmo quando algumas falharam.
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{
+		"data":      items,
+		"ordered":   ordered,
+		"succeeded": succeeded,
+		"failed":    len(items) - succeeded,
+	}})
+}
+
+//Converte e valida uma operação da requisição em uma stores.BulkOperation.
+func parseBulkOperation(request bulkOperationRequest) (stores.BulkOperation, error) {
+	operation := stores.BulkOperation{Op: stores.BulkOp(request.Op), ExpectedVersion: stores.AnyVersion}
+	if request.Version != nil {
+		operation.ExpectedVersion = *request.Version
+	}
+
+	switch operation.Op {
+	case stores.BulkCreate:
+		//Assim como em CreateUser, o ID é sempre gerado pelo servidor.
+		operation.User = models.User{Id: primitive.NewObjectID(), Name: request.User.Name, Location: request.User.Location, Title: request.User.Title}
+	case stores.BulkUpdate, stores.BulkDelete:
+		objId, err := primitive.ObjectIDFromHex(request.Id)
+		if err != nil {
+			return stores.BulkOperation{}, errors.New("Invalid user ID!")
+		}
+		operation.User = request.User
+		operation.User.Id = objId
+	default:
+		return stores.BulkOperation{}, errors.New("op must be create, update or delete")
+	}
+
+	if operation.Op != stores.BulkDelete {
+		if validationErr := validate.Struct(&operation.User); validationErr != nil {
+			return stores.BulkOperation{}, validationErr
+		}
+	}
+	return operation, nil
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+//Retorna o status de cada operação do resultado de POST /users/bulk.
+func bulkStatuses(t *testing.T, resp testResponse) []int {
+	t.Helper()
+	var statuses []int
+	for _, item := range resp.data(t).([]interface{}) {
+		statuses = append(statuses, int(item.(map[string]interface{})["status"].(float64)))
+	}
+	return statuses
+}
+
+func TestBulkUsersOrdered(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+
+	//A operação inválida (sem title) interrompe as seguintes; a anterior é aplicada.
+	resp := doRequest(t, app, "POST", "/users/bulk", `{"operations":[
+		{"op":"create","user":{"name":"Bruno","location":"Porto","title":"Manager"}},
+		{"op":"create","user":{"name":"Carla","location":"Porto"}},
+		{"op":"delete","id":"`+user.Id.Hex()+`"}
+	]}`).expect(t, http.StatusOK)
+
+	want := []int{http.StatusCreated, http.StatusBadRequest, http.StatusFailedDependency}
+	if got := bulkStatuses(t, resp); !slices.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	envelope := resp.body["data"].(map[string]interface{})
+	if envelope["ordered"] != true || envelope["succeeded"] != 1.0 || envelope["failed"] != 2.0 {
+		t.Errorf("summary = %v", envelope)
+	}
+	doRequest(t, app, "GET", "/user/"+user.Id.Hex(), "").expect(t, http.StatusOK)
+}
+
+func TestBulkUsersUnordered(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+
+	resp := doRequest(t, app, "POST", "/users/bulk", `{"ordered":false,"operations":[
+		{"op":"update","id":"`+user.Id.Hex()+`","version":5,"user":{"name":"Ana","location":"Porto","title":"Engineer"}},
+		{"op":"create","user":{"name":"Carla","location":"Porto"}},
+		{"op":"delete","id":"not-an-id"},
+		{"op":"delete","id":"`+user.Id.Hex()+`","version":1}
+	]}`).expect(t, http.StatusOK)
+
+	want := []int{http.StatusPreconditionFailed, http.StatusBadRequest, http.StatusBadRequest, http.StatusOK}
+	if got := bulkStatuses(t, resp); !slices.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	doRequest(t, app, "GET", "/user/"+user.Id.Hex(), "").expect(t, http.StatusNotFound)
+}
+
+func TestBulkUsersRejectsEmptyBatch(t *testing.T) {
+	app, _ := newTestUserApp(t)
+	doRequest(t, app, "POST", "/users/bulk", `{"operations":[]}`).expect(t, http.StatusBadRequest)
+}
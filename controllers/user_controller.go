@@ -1,222 +1,301 @@
 package controllers
 
 import (
-    "context" //Usado para gerenciar o contexto e controlar operações assíncronas, como limites de tempo.
-    "github.com/nathanfernande/golang-mongodb-api/configs"
-    "github.com/nathanfernande/golang-mongodb-api/models"
-    "github.com/nathanfernande/golang-mongodb-api/responses"
-    "net/http"
-    "time"
-
-    "github.com/go-playground/validator/v10" //Biblioteca para validação de structs, usada para verificar campos obrigatórios.
-    "github.com/gofiber/fiber/v2"
-    "go.mongodb.org/mongo-driver/bson/primitive"
-    "go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/bson"
+	"context" //Usado para gerenciar o contexto e controlar operações assíncronas, como limites de tempo.
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/patch"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/responses"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-//Declara uma variável que representa a coleção de usuários no MongoDB
-//Obtida através da função GetCollection do pacote configs
-var userCollection *mongo.Collection = configs.GetCollection(configs.DB, "users")
-//Inicializa o validador (validator/v10) para validar campos obrigatórios das requisições.
-var validate = validator.New()
+//UserController agrupa os handlers de usuários.
+//Em vez de acessar uma coleção global do MongoDB, os handlers usam o UserStore recebido no construtor,
+//o que permite trocar o backend (MongoDB ou memória) e testar os handlers sem um banco de dados.
+//Toda criação, edição, exclusão e restauração é registrada no AuditStore.
+type UserController struct {
+	store stores.UserStore
+	audit stores.AuditStore
+	//Tempo que um usuário excluído logicamente é mantido antes de poder ser removido por PurgeUsers.
+	retention time.Duration
+	timeouts  Timeouts
+}
+
+//Timeouts define os tempos limite das operações dos handlers no store.
+type Timeouts struct {
+	//Request limita as operações de uma requisição comum (um usuário, uma página ou o histórico).
+	Request time.Duration
+	//Bulk limita POST /users/bulk e Import limita POST /users/import, que podem gravar muitos usuários.
+	Bulk   time.Duration
+	Import time.Duration
+}
+
+//NewUserController cria um UserController que persiste os usuários no store informado
+//e registra as alterações no audit informado.
+//retention é a janela de retenção de usuários excluídos usada por PurgeUsers e timeouts limita as operações de cada handler.
+func NewUserController(store stores.UserStore, audit stores.AuditStore, retention time.Duration, timeouts Timeouts) *UserController {
+	return &UserController{store: store, audit: audit, retention: retention, timeouts: timeouts}
+}
+
+//Converte um erro retornado pelo store no erro da aplicação com que a requisição é respondida (ver problems.ErrorHandler):
+//404 para usuário inexistente, 400 para cursor ou consulta inválidos, 412 para conflito de versão,
+//409 para restauração de usuário não excluído, 404 para chave de API inexistente, 503 quando o MongoDB não responde
+//(timeout, falha de rede ou cliente desconectado) e 500 para os demais casos.
+func storeError(err error) *problems.Error {
+	switch {
+	case errors.Is(err, stores.ErrUserNotFound):
+		return problems.NotFound("User with specified ID not found!")
+	case errors.Is(err, stores.ErrInvalidCursor), errors.Is(err, stores.ErrInvalidQuery):
+		return problems.BadRequest(err.Error())
+	case errors.Is(err, stores.ErrVersionConflict):
+		return problems.PreconditionFailed(preconditionFailedMessage)
+	case errors.Is(err, stores.ErrUserNotDeleted):
+		return problems.Conflict("User with specified ID is not deleted!")
+	case errors.Is(err, stores.ErrAPIKeyNotFound):
+		return problems.NotFound("API key with specified ID not found!")
+	case mongo.IsTimeout(err), mongo.IsNetworkError(err), errors.Is(err, mongo.ErrClientDisconnected), errors.Is(err, context.DeadlineExceeded):
+		//Falhas passageiras: o cliente pode tentar de novo. Assim como em Internal, a causa vai apenas para o log.
+		problem := problems.Unavailable("The database is unavailable; retry later")
+		problem.Err = err
+		return problem
+	}
+	return problems.Internal(err)
+}
+
+//Retorna o status e a mensagem de uma operação que falhou em POST /users/bulk ou de uma linha de POST /users/import,
+//que vão no corpo da resposta 200 em vez de passar pelo problems.ErrorHandler.
+//Assim como no ErrorHandler, a causa das falhas internas vai apenas para o log.
+func storeErrorStatus(c *fiber.Ctx, err error) (int, string) {
+	problem := storeError(err)
+	if problem.Err != nil {
+		slog.ErrorContext(c.UserContext(), "store operation failed", "route", c.Route().Path, "error", err)
+	}
+	return problem.Status, problem.Detail
+}
+
+//Mensagem do 412 - Precondition Failed, usada quando o If-Match não corresponde à versão armazenada do usuário.
+const preconditionFailedMessage = "User was modified by another request; fetch it again and retry"
+
+//Define o cabeçalho ETag da resposta com a versão do usuário. O ETag é forte: "3" identifica exatamente a versão 3.
+func setUserETag(c *fiber.Ctx, user models.User) {
+	c.Set(fiber.HeaderETag, `"`+strconv.FormatInt(user.Version, 10)+`"`)
+}
+
+//Lê o cabeçalho If-Match e retorna a versão esperada do usuário.
+//Sem o cabeçalho, ou com "*", retorna stores.AnyVersion (escrita incondicional).
+//Retorna ok = false quando o valor nunca pode corresponder a uma versão (ETag fraco, lista ou valor malformado),
+//caso em que a requisição deve receber 412 - Precondition Failed.
+func ifMatchVersion(c *fiber.Ctx) (version int64, ok bool) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "" || ifMatch == "*" {
+		return stores.AnyVersion, true
+	}
+	unquoted, err := strconv.Unquote(ifMatch)
+	if err != nil || !strings.HasPrefix(ifMatch, `"`) {
+		return 0, false
+	}
+	version, err = strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 0 {
+		return 0, false
+	}
+	return version, true
+}
 
 //Define uma função que cria um novo usuário.
 //Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
-//retorna um erro 
-func CreateUser(c *fiber.Ctx) error {
-	//Cria um contexto com tempo limite de 10 segundos
+//retorna um erro
+func (uc *UserController) CreateUser(c *fiber.Ctx) error {
+	//Cria um contexto com o tempo limite de requisição configurado
 	//defer cancel(): Garante que os recursos associados ao contexto sejam liberados.
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-    var user models.User
-    defer cancel()
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
+	var user models.User
+	defer cancel()
 
-    //c.BodyParser(&user): Tenta converter o corpo da requisição em um objeto User
+	//parseBody(c, &user): Tenta converter o corpo da requisição em um objeto User
 	//Em caso de erro, retorna uma resposta HTTP 400 com uma mensagem de erro.
-    if err := c.BodyParser(&user); err != nil {
-        return c.Status(http.StatusBadRequest).JSON(responses.UserResponse{Status: http.StatusBadRequest, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-    }
+	if err := parseBody(c, &user); err != nil {
+		return problems.BadRequest(err.Error())
+	}
 
-    //Usa o validador para verificar se os campos obrigatórios (required) estão preenchidos
+	//Usa o validador para verificar se os campos obrigatórios (required) estão preenchidos
 	//Se houver falhas, retorna uma resposta HTTP 400.
-    if validationErr := validate.Struct(&user); validationErr != nil {
-        return c.Status(http.StatusBadRequest).JSON(responses.UserResponse{Status: http.StatusBadRequest, Message: "error", Data: &fiber.Map{"data": validationErr.Error()}})
-    }
+	if validationErr := validateBody(c, &user); validationErr != nil {
+		return validationProblem(c, validationErr)
+	}
 
 	//Cria um novo objeto User, gerando um ObjectID único para o campo Id.
-    newUser := models.User{
-        Id:       primitive.NewObjectID(),
-        Name:     user.Name,
-        Location: user.Location,
-        Title:    user.Title,
-    }
-
-	//Insere o novo usuário na coleção do MongoDB.
+	newUser := models.User{
+		Id:       primitive.NewObjectID(),
+		Name:     user.Name,
+		Location: user.Location,
+		Title:    user.Title,
+	}
+
+	//Grava o novo usuário no store.
 	//Em caso de erro, retorna uma resposta HTTP 500.
-    result, err := userCollection.InsertOne(ctx, newUser)
-    if err != nil {
-        return c.Status(http.StatusInternalServerError).JSON(responses.UserResponse{Status: http.StatusInternalServerError, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-    }
-
-	//Retorna uma resposta HTTP 201 com uma mensagem de sucesso e os detalhes do resultado.
-    return c.Status(http.StatusCreated).JSON(responses.UserResponse{Status: http.StatusCreated, Message: "success", Data: &fiber.Map{"data": result}})
+	createdUser, err := uc.store.Create(ctx, newUser)
+	if err != nil {
+		return storeError(err)
+	}
+	uc.recordAudit(ctx, c, models.AuditCreate, nil, &createdUser)
 
+	//Retorna uma resposta HTTP 201 com uma mensagem de sucesso, o usuário criado e o ETag da primeira versão.
+	setUserETag(c, createdUser)
+	return c.Status(http.StatusCreated).JSON(responses.UserResponse{Status: http.StatusCreated, Message: "success", Data: &fiber.Map{"data": createdUser}})
 
 	//Recebe o corpo da requisição.
 	//Valida os dados.
 	//Cria um objeto User com os dados recebidos.
-	//Insere o objeto na coleção do MongoDB.
+	//Grava o objeto no store.
 	//Retorna uma resposta JSON indicando sucesso ou falha.
 }
 
 //Define uma função que puxa os dados de um usuário.
 //Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
 //retorna um erro
-func GetAUser(c *fiber.Ctx) error {
-	//ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second): Cria um contexto com timeout de 10 segundos. Se a operação ultrapassar esse tempo, o contexto será cancelado automaticamente. A função cancel é usada para liberar os recursos manualmente, se necessário.
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	//Obtém o valor do parâmetro userId da URL. O Fiber armazena parâmetros capturados em rotas (ex.: /:userId) no contexto da requisição, que pode ser acessado com c.Params.
-    userId := c.Params("userId")
-	//var user models.User: Declara uma variável chamada user do tipo models.User, que armazenará os dados do usuário retornados do banco de dados.
-    var user models.User
+func (uc *UserController) GetAUser(c *fiber.Ctx) error {
+	//Cria um contexto com o timeout de requisição configurado. Se a operação ultrapassar esse tempo, o contexto será cancelado automaticamente.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
 	//defer cancel(): Garante que a função cancel seja chamada ao sair da função, liberando recursos associados ao contexto ctx.
-    defer cancel()
-
-	//Converte o valor de userId (uma string representando o ID do usuário) para um objeto ObjectID do MongoDB. Isso é necessário porque o MongoDB armazena IDs em um formato hexadecimal específico.
-    objId, _ := primitive.ObjectIDFromHex(userId)
-
-	//userCollection.FindOne(ctx, bson.M{"_id": objId}): Realiza uma consulta no banco de dados MongoDB para encontrar um documento na coleção userCollection onde o campo _id corresponde a objId.
-	//.Decode(&user): Decodifica o documento retornado do banco e o armazena na variável user.
-    err := userCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&user)
-
-	//Verifica se ocorreu um erro durante a consulta ao banco.
-		//Caso haja erro:
-			//Define o status HTTP como 500 - Internal Server Error.
-			//Retorna uma resposta JSON contendo:
-				//Status: O código de status HTTP.
-				//Message: A mensagem "error".
-				//Data: Um objeto com detalhes do erro.
-    if err != nil {
-        return c.Status(http.StatusInternalServerError).JSON(responses.UserResponse{Status: http.StatusInternalServerError, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-    }
-
-	//Se não houver erro:
-		//Define o status HTTP como 200 - OK.
-		//Retorna uma resposta JSON contendo:
-			//Status: O código de status HTTP.
-			//Message: A mensagem "success".
-			//Data: Um objeto com os dados do usuário encontrados.
-    return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": user}})
-
-
+	defer cancel()
+
+	//Converte o parâmetro userId da URL para um ObjectID. IDs inválidos recebem um 400 - Bad Request.
+	objId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return problems.BadRequest("Invalid user ID!")
+	}
+
+	//Usuários excluídos logicamente só são retornados com ?includeDeleted=true.
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Busca o usuário no store.
+	//Retorna 404 se ele não existir (ou estiver excluído) ou 500 se a consulta falhar.
+	user, err := uc.store.Get(ctx, objId, includeDeleted)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Se não houver erro, retorna 200 - OK com os dados do usuário encontrado.
+	//O ETag identifica a versão lida e pode ser enviado no If-Match de PUT, PATCH e DELETE.
+	setUserETag(c, user)
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": user}})
 
 	//A função GetAUser recebe um ID do usuário como parâmetro da URL.
 	//Converte o ID para o formato adequado (ObjectID).
-	//Busca o usuário correspondente no banco de dados MongoDB.
+	//Busca o usuário correspondente no store.
 	//Retorna os dados do usuário no formato JSON, ou um erro caso ocorra algum problema.
 }
 
 //Define uma função que edita um usuário já existente.
 //Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
 //retorna um erro
-func EditAUser(c *fiber.Ctx) error {
-	//ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second): Cria um contexto com timeout de 10 segundos. Após esse período, o contexto será cancelado.
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	//userId := c.Params("userId"): Obtém o parâmetro userId da URL.
-    userId := c.Params("userId")
-	// var user models.User: Declara uma variável user do tipo models.User para armazenar os dados enviados no corpo da requisição.
-    var user models.User
+func (uc *UserController) EditAUser(c *fiber.Ctx) error {
+	//Cria um contexto com o timeout de requisição configurado. Após esse período, o contexto será cancelado.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
+	//var user models.User: Declara uma variável user do tipo models.User para armazenar os dados enviados no corpo da requisição.
+	var user models.User
 	//defer cancel(): Garante que o contexto será liberado ao final da função.
-    defer cancel()
+	defer cancel()
 
 	//Converte userId (string) para o tipo ObjectID do MongoDB.
-    objId, _ := primitive.ObjectIDFromHex(userId)
-
-    //c.BodyParser(&user): Analisa o corpo da requisição e popula a variável user com os dados recebidos.
-	// Se ocorrer um erro (ex.: corpo da requisição inválido), retorna um status 400 - Bad Request com a mensagem de erro.
-    if err := c.BodyParser(&user); err != nil {
-        return c.Status(http.StatusBadRequest).JSON(responses.UserResponse{Status: http.StatusBadRequest, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-    }
-
-    //validate.Struct(&user): Usa uma biblioteca de validação (como go-playground/validator) para verificar se os campos obrigatórios do user estão preenchidos.
+	objId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return problems.BadRequest("Invalid user ID!")
+	}
+
+	//Lê a versão esperada do cabeçalho If-Match. Valores que nunca correspondem a uma versão recebem 412.
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return problems.PreconditionFailed(preconditionFailedMessage)
+	}
+
+	//parseBody(c, &user): Analisa o corpo da requisição e popula a variável user com os dados recebidos.
+	//Se ocorrer um erro (ex.: corpo da requisição inválido), retorna um status 400 - Bad Request com a mensagem de erro.
+	if err := parseBody(c, &user); err != nil {
+		return problems.BadRequest(err.Error())
+	}
+
+	//validateBody(c, &user): Verifica se os campos obrigatórios do user estão preenchidos.
 	//Se os dados forem inválidos, retorna um status 400 - Bad Request com detalhes da validação.
-    if validationErr := validate.Struct(&user); validationErr != nil {
-        return c.Status(http.StatusBadRequest).JSON(responses.UserResponse{Status: http.StatusBadRequest, Message: "error", Data: &fiber.Map{"data": validationErr.Error()}})
-    }
-
-	//Cria um objeto no formato BSON (Binary JSON) contendo os campos que serão atualizados no banco de dados.
-    update := bson.M{"name": user.Name, "location": user.Location, "title": user.Title}
-
-	//userCollection.UpdateOne: Atualiza os dados do usuário no banco de dados.
-		//O filtro bson.M{"_id": objId} seleciona o documento com o ID correspondente.
-		//O operador "$set" especifica os campos que devem ser atualizados.
-    result, err := userCollection.UpdateOne(ctx, bson.M{"id": objId}, bson.M{"$set": update})
-	//Se ocorrer algum problema durante a atualização, retorna um status 500 - Internal Server Error.
-    if err != nil {
-        return c.Status(http.StatusInternalServerError).JSON(responses.UserResponse{Status: http.StatusInternalServerError, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-    }
-
-    var updatedUser models.User
-	//result.MatchedCount == 1: Verifica se algum documento foi encontrado e atualizado.
-    if result.MatchedCount == 1 {
-		//userCollection.FindOne: Busca o documento atualizado no banco de dados.
-		//.Decode(&updatedUser): Decodifica o documento encontrado e armazena os dados em updatedUser.
-        err := userCollection.FindOne(ctx, bson.M{"id": objId}).Decode(&updatedUser)
-		//Caso não consiga buscar os dados atualizados, retorna um status 500 - Internal Server Error.
-        if err != nil {
-            return c.Status(http.StatusInternalServerError).JSON(responses.UserResponse{Status: http.StatusInternalServerError, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-        }
-    }
-
-	//Retorna uma resposta com status 200 - OK e os dados do usuário atualizados no formato JSON.
-    return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": updatedUser}})
-
+	if validationErr := validateBody(c, &user); validationErr != nil {
+		return validationProblem(c, validationErr)
+	}
+
+	//O ID sempre vem da URL, nunca do corpo da requisição.
+	user.Id = objId
+
+	//Atualiza os campos name, location e title no store e recebe os documentos antes e depois da alteração.
+	//Retorna 404 se o usuário não existir, 412 se a versão do If-Match estiver desatualizada ou 500 se a atualização falhar.
+	before, updatedUser, err := uc.writeUser(ctx, objId, expectedVersion, func(before models.User) (models.User, error) {
+		return uc.store.Update(ctx, user, before.Version)
+	})
+	if err != nil {
+		return storeError(err)
+	}
+	uc.recordAudit(ctx, c, models.AuditUpdate, &before, &updatedUser)
+
+	//Retorna uma resposta com status 200 - OK, os dados do usuário atualizados no formato JSON e o ETag da nova versão.
+	setUserETag(c, updatedUser)
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": updatedUser}})
 
 	//Obtém o userId da URL e converte para ObjectID.
 	//Valida os dados enviados no corpo da requisição.
-	//Atualiza os campos no banco de dados.
-	//Recupera os dados atualizados do usuário.
+	//Atualiza os campos no store.
 	//Retorna uma resposta com os dados atualizados ou um erro, se houver.
 }
 
 //Define uma função que deleta um usuário.
 //Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
 //retorna um erro
-func DeleteAUser(c *fiber.Ctx) error {
-	//ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second): Cria um contexto com timeout de 10 segundos para a operação de exclusão.
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	//userId := c.Params("userId"): Obtém o parâmetro userId da URL.
-    userId := c.Params("userId")
+func (uc *UserController) DeleteAUser(c *fiber.Ctx) error {
+	//Cria um contexto com o timeout de requisição configurado para a operação de exclusão.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
 	//defer cancel(): Garante que o contexto será cancelado ao sair da função, liberando recursos.
-    defer cancel()
+	defer cancel()
 
 	//Converte o userId (string) para um objeto ObjectID, que é o formato utilizado pelo MongoDB para IDs.
-    objId, _ := primitive.ObjectIDFromHex(userId)
-
-	//userCollection.DeleteOne(ctx, bson.M{"_id": objId}): Executa a operação de exclusão no banco de dados. O filtro bson.M{"_id": objId} busca o documento com o ID fornecido.
-    result, err := userCollection.DeleteOne(ctx, bson.M{"id": objId})
-	// Caso ocorra um erro durante a exclusão, retorna uma resposta com status 500 - Internal Server Error e detalhes do erro.
-    if err != nil {
-        return c.Status(http.StatusInternalServerError).JSON(responses.UserResponse{Status: http.StatusInternalServerError, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-    }
-
-	//result.DeletedCount < 1: Verifica se nenhum documento foi excluído. Isso significa que o ID especificado não foi encontrado no banco.
-	//Retorna um status 404 - Not Found e uma mensagem indicando que o usuário com o ID fornecido não foi encontrado.
-    if result.DeletedCount < 1 {
-        return c.Status(http.StatusNotFound).JSON(
-            responses.UserResponse{Status: http.StatusNotFound, Message: "error", Data: &fiber.Map{"data": "User with specified ID not found!"}},
-        )
-    }
+	objId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return problems.BadRequest("Invalid user ID!")
+	}
+
+	//Lê a versão esperada do cabeçalho If-Match. Valores que nunca correspondem a uma versão recebem 412.
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return problems.PreconditionFailed(preconditionFailedMessage)
+	}
+
+	//Exclui o usuário logicamente: ele deixa de aparecer nas consultas, mas pode ser restaurado até ser removido por PurgeUsers.
+	//Retorna 404 se o ID não corresponder a nenhum usuário ativo, 412 se a versão do If-Match estiver desatualizada ou 500 se a exclusão falhar.
+	before, deletedUser, err := uc.writeUser(ctx, objId, expectedVersion, func(before models.User) (models.User, error) {
+		return uc.store.Delete(ctx, objId, before.Version)
+	})
+	if err != nil {
+		return storeError(err)
+	}
+	uc.recordAudit(ctx, c, models.AuditDelete, &before, &deletedUser)
 
 	//Retorna uma resposta com status 200 - OK e uma mensagem indicando que o usuário foi excluído com sucesso.
-    return c.Status(http.StatusOK).JSON(
-        responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": "User successfully deleted!"}},
-    )
-
+	return c.Status(http.StatusOK).JSON(
+		responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": "User successfully deleted!"}},
+	)
 
 	//Obtém o ID do usuário a partir da URL e o converte para ObjectID.
-	//Executa a exclusão do documento correspondente no MongoDB.
-	//Verifica se algum documento foi excluído:
+	//Marca o usuário correspondente como excluído no store.
 	//Retorna erro 500 se houver problema na operação.
 	//Retorna erro 404 se o ID fornecido não corresponder a nenhum usuário.
 	//Caso contrário, retorna sucesso com status 200.
@@ -225,56 +304,200 @@ func DeleteAUser(c *fiber.Ctx) error {
 //Define uma função que puxa os dados de todos os usuários.
 //Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
 //retorna um erro
-func GetAllUsers(c *fiber.Ctx) error {
-	//Cria um contexto (ctx) com um tempo limite de 10 segundos para operações assíncronas (útil para evitar que a função fique bloqueada indefinidamente).
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	//Declara um slice (lista) vazio chamado users, que armazenará os dados dos usuários retornados do banco.
-	//models.User é um modelo definido previamente.
-    var users []models.User
+func (uc *UserController) GetAllUsers(c *fiber.Ctx) error {
+	//Cria um contexto (ctx) com o tempo limite de requisição configurado para operações assíncronas.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
 	//defer cancel() garante que o contexto será cancelado ao final da execução da função, liberando recursos.
-    defer cancel()
-
-	//Executa uma busca na coleção userCollection (uma referência à coleção de usuários no MongoDB).
-	//O bson.M{} representa um filtro vazio, ou seja, todos os documentos (usuários) serão retornados.
-	//A busca usa o contexto ctx criado anteriormente.
-	//results é um iterador que permite percorrer os documentos retornados.
-    results, err := userCollection.Find(ctx, bson.M{})
-
-	//Verifica se ocorreu algum erro na consulta ao banco de dados.
-	//Caso positivo, retorna uma resposta HTTP com status 500 (erro interno do servidor) e inclui o erro na resposta no formato JSON.
-    if err != nil {
-        return c.Status(http.StatusInternalServerError).JSON(responses.UserResponse{Status: http.StatusInternalServerError, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-    }
-
-    //Adiciona um defer para fechar o iterador results automaticamente após o término do uso, liberando os recursos do MongoDB.
-    defer results.Close(ctx)
-
-	//O for results.Next(ctx) percorre cada documento retornado pela consulta.
-	//Em cada iteração:
-		//Declara uma variável singleUser para armazenar os dados de um usuário.
-		//Decodifica o documento atual em singleUser usando results.Decode(&singleUser). Caso ocorra um erro durante a decodificação, retorna uma resposta HTTP com status 500.
-		//adiciona o usuário decodificado ao slice users com append(users, singleUser).
-
-    for results.Next(ctx) {
-        var singleUser models.User
-        if err = results.Decode(&singleUser); err != nil {
-            return c.Status(http.StatusInternalServerError).JSON(responses.UserResponse{Status: http.StatusInternalServerError, Message: "error", Data: &fiber.Map{"data": err.Error()}})
-        }
-
-        users = append(users, singleUser)
-    }
-
-
-	//Após percorrer todos os documentos, retorna uma resposta HTTP com status 200 (OK).
-	//A resposta inclui os usuários encontrados no campo Data, encapsulados em um objeto de resposta (responses.UserResponse).
-    return c.Status(http.StatusOK).JSON(
-        responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": users}},
-    )
-
-	
-	//Criação de contexto: Um contexto com timeout de 10 segundos é criado para limitar a execução da operação.
-	//Consulta no banco: Todos os documentos (usuários) são buscados na coleção.
-	//Iteração nos resultados: Os documentos retornados são iterados, decodificados em uma estrutura models.User e adicionados a um slice (users).
-	//Tratamento de erros: Caso ocorra erro na consulta ou decodificação, uma resposta de erro HTTP 500 é retornada.
-	//Resposta final: Após processar os dados, retorna uma resposta HTTP 200 com a lista de usuários.
-}
\ No newline at end of file
+	defer cancel()
+
+	//Lê os parâmetros de paginação (limit e cursor), filtros e ordenação da query string.
+	//Campos ou operadores desconhecidos retornam 400 - Bad Request.
+	query, err := parseUserQuery(c)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Busca uma página de usuários no store.
+	//Cursores inválidos recebem 400; demais erros, 500.
+	page, err := uc.store.List(ctx, query)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//next_cursor é null na última página.
+	var nextCursor *string
+	if page.NextCursor != "" {
+		nextCursor = &page.NextCursor
+	}
+
+	//Retorna uma resposta HTTP com status 200 (OK) com os usuários da página e o cursor da próxima página.
+	return c.Status(http.StatusOK).JSON(
+		responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": page.Users, "next_cursor": nextCursor, "limit": query.Limit}},
+	)
+
+	//Criação de contexto: Um contexto com o timeout de requisição configurado é criado para limitar a execução da operação.
+	//Consulta: Lê limit, cursor, filtros e ordenação da query string, aplicando o teto de página do servidor.
+	//Consulta no store: Uma página de usuários filtrados é buscada a partir do cursor.
+	//Tratamento de erros: Caso ocorra erro na consulta, uma resposta de erro HTTP 500 é retornada.
+	//Resposta final: Após processar os dados, retorna uma resposta HTTP 200 com a página de usuários e o next_cursor.
+}
+
+//Define uma função que faz uma busca textual nos usuários.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) SearchUsers(c *fiber.Ctx) error {
+	//Cria um contexto com o tempo limite de requisição configurado para a busca.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
+	defer cancel()
+
+	//O parâmetro q é obrigatório e contém os termos buscados em name, title e location.
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		return problems.BadRequest("query parameter q is required")
+	}
+
+	//limit segue as mesmas regras da listagem (padrão e teto do servidor).
+	limit, err := parseLimit(c)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Busca os usuários no store, já ordenados do mais para o menos relevante.
+	users, err := uc.store.Search(ctx, text, limit)
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Retorna uma resposta HTTP com status 200 (OK) com os usuários encontrados.
+	return c.Status(http.StatusOK).JSON(
+		responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": users}},
+	)
+}
+
+//Define uma função que atualiza parcialmente um usuário.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) PatchAUser(c *fiber.Ctx) error {
+	//Cria um contexto com o timeout de requisição configurado para a leitura e a gravação do usuário.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
+	defer cancel()
+
+	//Converte o parâmetro userId da URL para um ObjectID. IDs inválidos recebem um 400 - Bad Request.
+	objId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return problems.BadRequest("Invalid user ID!")
+	}
+
+	//Só aceita os dois formatos de patch; qualquer outro Content-Type recebe 415 - Unsupported Media Type.
+	contentType := c.Get(fiber.HeaderContentType)
+	if patch.MediaType(contentType) == "" {
+		return problems.UnsupportedMediaType(patch.ErrUnsupportedMediaType.Error())
+	}
+
+	//Lê a versão esperada do cabeçalho If-Match. Valores que nunca correspondem a uma versão recebem 412.
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return problems.PreconditionFailed(preconditionFailedMessage)
+	}
+
+	//Busca o documento atual, que é o ponto de partida do patch.
+	//Se o cliente enviou If-Match e a versão atual já é outra, retorna 412 sem aplicar o patch.
+	user, err := uc.store.Get(ctx, objId, false)
+	if err != nil {
+		return storeError(err)
+	}
+	if expectedVersion != stores.AnyVersion && user.Version != expectedVersion {
+		return problems.PreconditionFailed(preconditionFailedMessage)
+	}
+
+	//Aplica o patch sobre o usuário.
+	//Patches malformados ou operações que falham (ex.: "test" ou caminho inexistente) recebem 400 - Bad Request.
+	patchedUser, err := patch.ApplyUser(contentType, user, c.Body())
+	if err != nil {
+		return problems.BadRequest(err.Error())
+	}
+
+	//O ID faz parte da URL e não pode ser alterado pelo patch.
+	if patchedUser.Id != objId {
+		return problems.Validation("id cannot be changed")
+	}
+	//A versão é controlada pelo servidor; alterações feitas pelo patch são ignoradas.
+	patchedUser.Version = user.Version
+
+	//Valida o documento final com as mesmas regras de models.User usadas em CreateUser e EditAUser.
+	if validationErr := validateBody(c, &patchedUser); validationErr != nil {
+		return validationProblem(c, validationErr)
+	}
+
+	//Grava o documento atualizado no store, exigindo que a versão ainda seja a que foi lida.
+	//Assim, uma alteração concorrente entre a leitura e a gravação resulta em 412 em vez de ser sobrescrita.
+	updatedUser, err := uc.store.Update(ctx, patchedUser, user.Version)
+	if err != nil {
+		return storeError(err)
+	}
+	uc.recordAudit(ctx, c, models.AuditUpdate, &user, &updatedUser)
+
+	//Retorna uma resposta com status 200 - OK, os dados do usuário atualizados no formato JSON e o ETag da nova versão.
+	setUserETag(c, updatedUser)
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": updatedUser}})
+
+	//Obtém o userId da URL e converte para ObjectID.
+	//Busca o usuário atual e aplica o merge patch ou o JSON patch sobre ele.
+	//Valida o documento resultante.
+	//Grava o usuário atualizado e retorna os novos dados ou um erro, se houver.
+}
+
+//Define uma função que restaura um usuário excluído logicamente.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) RestoreAUser(c *fiber.Ctx) error {
+	//Cria um contexto com o timeout de requisição configurado para a restauração.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
+	defer cancel()
+
+	//Converte o parâmetro userId da URL para um ObjectID. IDs inválidos recebem um 400 - Bad Request.
+	objId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return problems.BadRequest("Invalid user ID!")
+	}
+
+	//Restaura o usuário no store.
+	//Retorna 404 se ele não existir (ou já tiver sido removido definitivamente), 409 se ele não estiver excluído ou 500 se a operação falhar.
+	//O usuário é lido antes (incluindo excluídos) para registrar o estado anterior na auditoria.
+	before, err := uc.store.Get(ctx, objId, true)
+	if err != nil {
+		return storeError(err)
+	}
+	restoredUser, err := uc.store.Restore(ctx, objId)
+	if err != nil {
+		return storeError(err)
+	}
+	uc.recordAudit(ctx, c, models.AuditRestore, &before, &restoredUser)
+
+	//Retorna uma resposta com status 200 - OK, os dados do usuário restaurado e o ETag da nova versão.
+	setUserETag(c, restoredUser)
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": restoredUser}})
+}
+
+//Define uma função administrativa que remove definitivamente os usuários excluídos há mais tempo que a janela de retenção.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) PurgeUsers(c *fiber.Ctx) error {
+	//Cria um contexto com o timeout de requisição configurado para a remoção.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Request)
+	defer cancel()
+
+	//Só são removidos os usuários cuja exclusão é anterior a agora menos a janela de retenção.
+	deletedBefore := time.Now().Add(-uc.retention)
+	//Cada usuário removido ganha um registro purge na auditoria, inclusive os removidos antes de uma falha.
+	purged, err := uc.store.Purge(ctx, deletedBefore)
+	for i := range purged {
+		uc.recordAudit(ctx, c, models.AuditPurge, &purged[i], nil)
+	}
+	if err != nil {
+		return storeError(err)
+	}
+
+	//Retorna uma resposta com status 200 - OK com a quantidade de usuários removidos.
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{"data": fiber.Map{"purged": len(purged), "deletedBefore": deletedBefore}}})
+}
@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//Monta um servidor com os handlers de usuários sobre um MemoryUserStore, sem autenticação nem MongoDB.
+func newTestUserApp(t *testing.T) (*fiber.App, *stores.MemoryUserStore) {
+	t.Helper()
+	store := stores.NewMemoryUserStore()
+	uc := NewUserController(store, stores.NewMemoryAuditStore(), time.Hour, Timeouts{Request: time.Second, Bulk: time.Second, Import: time.Second})
+
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Post("/user", uc.CreateUser)
+	app.Get("/user/:userId", uc.GetAUser)
+	app.Put("/user/:userId", uc.EditAUser)
+	app.Patch("/user/:userId", uc.PatchAUser)
+	app.Delete("/user/:userId", uc.DeleteAUser)
+	app.Post("/user/:userId/restore", uc.RestoreAUser)
+	app.Get("/user/:userId/history", uc.GetUserHistory)
+	app.Get("/users", uc.GetAllUsers)
+	app.Get("/users/search", uc.SearchUsers)
+	app.Get("/users/stream", uc.StreamUsers)
+	app.Post("/users/bulk", uc.BulkUsers)
+	app.Get("/users/export.csv", uc.ExportUsersCSV)
+	app.Post("/users/import", uc.ImportUsersCSV)
+	app.Post("/admin/users/purge", uc.PurgeUsers)
+	return app, store
+}
+
+//Resposta decodificada de uma requisição de teste.
+type testResponse struct {
+	status int
+	header http.Header
+	raw    string
+	body   map[string]interface{}
+}
+
+//Envia a requisição com app.Test. header alterna nome e valor; sem Content-Type, o corpo é enviado como JSON.
+func doRequest(t *testing.T, app *fiber.App, method, target, body string, header ...string) testResponse {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := testResponse{status: resp.StatusCode, header: resp.Header, raw: string(raw)}
+	//Respostas que não são um objeto JSON (ex.: CSV) ficam só em raw.
+	_ = json.Unmarshal(raw, &response.body)
+	return response
+}
+
+//Retorna o membro data do envelope de sucesso.
+func (r testResponse) data(t *testing.T) interface{} {
+	t.Helper()
+	envelope, ok := r.body["data"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("response has no data envelope: %s", r.raw)
+	}
+	return envelope["data"]
+}
+
+//Retorna o usuário do envelope de sucesso.
+func (r testResponse) user(t *testing.T) map[string]interface{} {
+	t.Helper()
+	user, ok := r.data(t).(map[string]interface{})
+	if !ok {
+		t.Fatalf("response data is not a user: %s", r.raw)
+	}
+	return user
+}
+
+func (r testResponse) expect(t *testing.T, status int) testResponse {
+	t.Helper()
+	if r.status != status {
+		t.Fatalf("status = %d, want %d: %s", r.status, status, r.raw)
+	}
+	return r
+}
+
+func (r testResponse) expectProblem(t *testing.T, status int, problemType string) testResponse {
+	t.Helper()
+	r.expect(t, status)
+	if contentType := r.header.Get(fiber.HeaderContentType); contentType != problems.ContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, problems.ContentType)
+	}
+	if r.body["type"] != problemType {
+		t.Errorf("problem type = %v, want %s", r.body["type"], problemType)
+	}
+	return r
+}
+
+//Grava um usuário diretamente no store, sem passar pelos handlers.
+func createTestUser(t *testing.T, store stores.UserStore, name, location, title string) models.User {
+	t.Helper()
+	user, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: name, Location: location, Title: title})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCreateUser(t *testing.T) {
+	app, store := newTestUserApp(t)
+
+	resp := doRequest(t, app, "POST", "/user", `{"id":"65a1f0c2e4b0a1b2c3d4e5f6","name":"Ana","location":"Lisbon","title":"Engineer"}`).expect(t, http.StatusCreated)
+	if etag := resp.header.Get(fiber.HeaderETag); etag != `"1"` {
+		t.Errorf("ETag = %q, want \"1\"", etag)
+	}
+	created := resp.user(t)
+	if created["name"] != "Ana" || created["location"] != "Lisbon" || created["title"] != "Engineer" || created["version"] != 1.0 {
+		t.Errorf("created user = %v", created)
+	}
+	//O ID é sempre gerado pelo servidor.
+	if created["id"] == "65a1f0c2e4b0a1b2c3d4e5f6" {
+		t.Error("the server used the id from the request body")
+	}
+	id, _ := primitive.ObjectIDFromHex(created["id"].(string))
+	if _, err := store.Get(context.Background(), id, false); err != nil {
+		t.Errorf("created user is not in the store: %v", err)
+	}
+
+	doRequest(t, app, "POST", "/user", `{"name":`).expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+	doRequest(t, app, "POST", "/user", `{"name":"Ana"}`).expectProblem(t, http.StatusBadRequest, problems.TypeValidation)
+}
+
+func TestGetAUser(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+
+	resp := doRequest(t, app, "GET", "/user/"+user.Id.Hex(), "").expect(t, http.StatusOK)
+	if got := resp.user(t); got["id"] != user.Id.Hex() || got["name"] != "Ana" {
+		t.Errorf("user = %v", got)
+	}
+	if etag := resp.header.Get(fiber.HeaderETag); etag != `"1"` {
+		t.Errorf("ETag = %q, want \"1\"", etag)
+	}
+
+	doRequest(t, app, "GET", "/user/not-an-id", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+	doRequest(t, app, "GET", "/user/"+primitive.NewObjectID().Hex(), "").expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+}
+
+func TestEditAUser(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	target := "/user/" + user.Id.Hex()
+
+	resp := doRequest(t, app, "PUT", target, `{"name":"Ana Maria","location":"Porto","title":"Manager"}`, fiber.HeaderIfMatch, `"1"`).expect(t, http.StatusOK)
+	if got := resp.user(t); got["name"] != "Ana Maria" || got["location"] != "Porto" || got["version"] != 2.0 {
+		t.Errorf("updated user = %v", got)
+	}
+	if etag := resp.header.Get(fiber.HeaderETag); etag != `"2"` {
+		t.Errorf("ETag = %q, want \"2\"", etag)
+	}
+
+	//Um If-Match com a versão antiga não sobrescreve a alteração anterior.
+	doRequest(t, app, "PUT", target, `{"name":"Ana","location":"Lisbon","title":"Engineer"}`, fiber.HeaderIfMatch, `"1"`).
+		expectProblem(t, http.StatusPreconditionFailed, problems.TypePreconditionFailed)
+	stored, _ := store.Get(context.Background(), user.Id, false)
+	if stored.Name != "Ana Maria" {
+		t.Errorf("stored name = %q after a stale If-Match", stored.Name)
+	}
+
+	doRequest(t, app, "PUT", target, `{"name":"Ana"}`).expectProblem(t, http.StatusBadRequest, problems.TypeValidation)
+	doRequest(t, app, "PUT", "/user/"+primitive.NewObjectID().Hex(), `{"name":"Ana","location":"Lisbon","title":"Engineer"}`).
+		expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+}
+
+func TestDeleteAUser(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	target := "/user/" + user.Id.Hex()
+
+	doRequest(t, app, "DELETE", target, "", fiber.HeaderIfMatch, `"7"`).expectProblem(t, http.StatusPreconditionFailed, problems.TypePreconditionFailed)
+	doRequest(t, app, "DELETE", target, "").expect(t, http.StatusOK)
+
+	//O usuário excluído logicamente some das leituras, mas continua acessível com includeDeleted.
+	doRequest(t, app, "GET", target, "").expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+	if deleted := doRequest(t, app, "GET", target+"?includeDeleted=true", "").expect(t, http.StatusOK).user(t); deleted["deletedAt"] == nil {
+		t.Errorf("deleted user has no deletedAt: %v", deleted)
+	}
+	doRequest(t, app, "DELETE", target, "").expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+}
+
+func TestGetAllUsers(t *testing.T) {
+	app, store := newTestUserApp(t)
+	for i := 0; i < 5; i++ {
+		createTestUser(t, store, "User "+strconv.Itoa(i), "Lisbon", "Engineer")
+	}
+
+	//Percorre todas as páginas seguindo next_cursor.
+	seen := map[string]bool{}
+	target := "/users?limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("more pages than expected")
+		}
+		resp := doRequest(t, app, "GET", target, "").expect(t, http.StatusOK)
+		users := resp.data(t).([]interface{})
+		if len(users) > 2 {
+			t.Fatalf("page has %d users, want at most 2", len(users))
+		}
+		for _, user := range users {
+			id := user.(map[string]interface{})["id"].(string)
+			if seen[id] {
+				t.Errorf("user %s appears in more than one page", id)
+			}
+			seen[id] = true
+		}
+		target = ""
+		if cursor, ok := resp.body["data"].(map[string]interface{})["next_cursor"].(string); ok {
+			target = "/users?limit=2&cursor=" + cursor
+		}
+	}
+	if len(seen) != 5 {
+		t.Errorf("listed %d users, want 5", len(seen))
+	}
+
+	doRequest(t, app, "GET", "/users?limit=0", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+	doRequest(t, app, "GET", "/users?cursor=bogus", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+}
+
+func TestRestoreAUser(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	target := "/user/" + user.Id.Hex()
+
+	//Um usuário ativo não pode ser restaurado.
+	doRequest(t, app, "POST", target+"/restore", "").expectProblem(t, http.StatusConflict, problems.TypeConflict)
+
+	doRequest(t, app, "DELETE", target, "").expect(t, http.StatusOK)
+	resp := doRequest(t, app, "POST", target+"/restore", "").expect(t, http.StatusOK)
+	if restored := resp.user(t); restored["deletedAt"] != nil || restored["version"] != 3.0 {
+		t.Errorf("restored user = %v", restored)
+	}
+	if etag := resp.header.Get(fiber.HeaderETag); etag != `"3"` {
+		t.Errorf("ETag = %q, want \"3\"", etag)
+	}
+	doRequest(t, app, "GET", target, "").expect(t, http.StatusOK)
+
+	doRequest(t, app, "POST", target+"/restore", "").expectProblem(t, http.StatusConflict, problems.TypeConflict)
+	doRequest(t, app, "POST", "/user/"+primitive.NewObjectID().Hex()+"/restore", "").expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+	doRequest(t, app, "POST", "/user/not-an-id/restore", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+}
+
+func TestPurgeUsers(t *testing.T) {
+	app, store := newTestUserApp(t)
+	active := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	//A janela de retenção do servidor de teste é de uma hora.
+	deletedAt := func(ago time.Duration) models.User {
+		t.Helper()
+		when := time.Now().Add(-ago)
+		user, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: "Old", Location: "Porto", Title: "Engineer", DeletedAt: &when})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	expired := deletedAt(2 * time.Hour)
+	recent := deletedAt(30 * time.Minute)
+
+	resp := doRequest(t, app, "POST", "/admin/users/purge", "").expect(t, http.StatusOK)
+	if purged := resp.data(t).(map[string]interface{})["purged"]; purged != 1.0 {
+		t.Errorf("purged = %v, want 1", purged)
+	}
+	if _, err := store.Get(context.Background(), expired.Id, true); err != stores.ErrUserNotFound {
+		t.Errorf("user deleted before the retention window: Get error = %v, want ErrUserNotFound", err)
+	}
+	for _, user := range []models.User{active, recent} {
+		if _, err := store.Get(context.Background(), user.Id, true); err != nil {
+			t.Errorf("user %s was purged: %v", user.Name, err)
+		}
+	}
+}
+
+func TestIncludeDeleted(t *testing.T) {
+	app, store := newTestUserApp(t)
+	createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	deleted := createTestUser(t, store, "Bruno", "Porto", "Engineer")
+	doRequest(t, app, "DELETE", "/user/"+deleted.Id.Hex(), "").expect(t, http.StatusOK)
+
+	names := func(target string) []string {
+		t.Helper()
+		var names []string
+		for _, user := range doRequest(t, app, "GET", target, "").expect(t, http.StatusOK).data(t).([]interface{}) {
+			names = append(names, user.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+	if got := names("/users?sort=name"); strings.Join(got, ",") != "Ana" {
+		t.Errorf("GET /users = %v, want only the active user", got)
+	}
+	if got := names("/users?sort=name&includeDeleted=true"); strings.Join(got, ",") != "Ana,Bruno" {
+		t.Errorf("GET /users?includeDeleted=true = %v, want both users", got)
+	}
+	if got := names("/users?sort=name&includeDeleted=false"); strings.Join(got, ",") != "Ana" {
+		t.Errorf("GET /users?includeDeleted=false = %v, want only the active user", got)
+	}
+
+	doRequest(t, app, "GET", "/user/"+deleted.Id.Hex()+"?includeDeleted=false", "").expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+	doRequest(t, app, "GET", "/user/"+deleted.Id.Hex()+"?includeDeleted=yes", "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+}
+
+func TestStoreError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantType   string
+	}{
+		{"not found", stores.ErrUserNotFound, http.StatusNotFound, problems.TypeNotFound},
+		{"version conflict", stores.ErrVersionConflict, http.StatusPreconditionFailed, problems.TypePreconditionFailed},
+		{"network error", mongo.CommandError{Message: "connection reset", Labels: []string{"NetworkError"}}, http.StatusServiceUnavailable, problems.TypeUnavailable},
+		{"deadline exceeded", fmt.Errorf("find user: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, problems.TypeUnavailable},
+		{"client disconnected", mongo.ErrClientDisconnected, http.StatusServiceUnavailable, problems.TypeUnavailable},
+		{"other error", errors.New("duplicate key"), http.StatusInternalServerError, problems.TypeInternal},
+	}
+	for _, tt := range tests {
+		problem := storeError(tt.err)
+		if problem.Status != tt.wantStatus || problem.Type != tt.wantType {
+			t.Errorf("%s: storeError = %d %s, want %d %s", tt.name, problem.Status, problem.Type, tt.wantStatus, tt.wantType)
+		}
+		if tt.wantStatus >= http.StatusInternalServerError && problem.Err == nil {
+			t.Errorf("%s: storeError().Err = nil, want the store error for the log", tt.name)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/responses"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Colunas do CSV de usuários, na ordem em que são exportadas. O cabeçalho usa os mesmos nomes dos campos JSON de models.User.
+//Na importação, apenas as colunas com set são lidas; as demais (id, version e deletedAt) são aceitas para que um arquivo
+//exportado possa ser importado de novo, mas são ignoradas, já que o servidor gera o ID e a versão e importa usuários ativos.
+var userCSVColumns = []struct {
+	header string
+	get    func(models.User) string
+	set    func(*models.User, string)
+}{
+	{"id", func(u models.User) string { return u.Id.Hex() }, nil},
+	{"name", func(u models.User) string { return u.Name }, func(u *models.User, v string) { u.Name = v }},
+	{"location", func(u models.User) string { return u.Location }, func(u *models.User, v string) { u.Location = v }},
+	{"title", func(u models.User) string { return u.Title }, func(u *models.User, v string) { u.Title = v }},
+	{"version", func(u models.User) string { return strconv.FormatInt(u.Version, 10) }, nil},
+	//Vazio para usuários ativos; com includeDeleted=true, distingue os excluídos logicamente.
+	{"deletedAt", func(u models.User) string {
+		if u.DeletedAt == nil {
+			return ""
+		}
+		return u.DeletedAt.UTC().Format(time.RFC3339)
+	}, nil},
+}
+
+//Caracteres que fazem uma planilha interpretar a célula como fórmula (CSV injection), além de tab e CR,
+//que algumas planilhas descartam antes de fazer a mesma verificação.
+const csvFormulaChars = "=+-@\t\r"
+
+//Escapa uma célula exportada que começa com um caractere de fórmula, prefixando-a com um apóstrofo,
+//para que a planilha a mostre como texto em vez de executá-la (ex.: "=cmd|' /C calc'!A0").
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaChars, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+//Desfaz escapeCSVCell na importação, para que um arquivo exportado possa ser importado de novo sem ganhar apóstrofos.
+func unescapeCSVCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(csvFormulaChars, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+//Resultado da importação de uma linha do CSV. Line é o número da linha no arquivo (o cabeçalho é a linha 1).
+type csvRowResponse struct {
+	Line   int    `json:"line"`
+	Status int    `json:"status"`
+	Id     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+	//Errors lista as colunas que não passaram na validação (ver FieldError).
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+//Define uma função que exporta todos os usuários em CSV.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) ExportUsersCSV(c *fiber.Ctx) error {
+	//includeDeleted=true também exporta os usuários excluídos logicamente.
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		return storeError(err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="users.csv"`)
+
+	//Assim como em GET /users/stream, a consulta é validada antes de a resposta começar.
+	query := stores.UserQuery{IncludeDeleted: includeDeleted}
+	if err := query.Validate(); err != nil {
+		return storeError(err)
+	}
+
+	//O corpo é escrito depois que o handler retorna, percorrendo o cursor do store (Stream):
+	//cada linha é escrita e descartada, então a memória usada não depende do tamanho da coleção.
+	//Assim como em StreamUsers, o contexto da requisição é guardado antes para levar o trace aos comandos do cursor.
+	store, parent := uc.store, c.UserContext()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		writer := csv.NewWriter(w)
+		record := make([]string, len(userCSVColumns))
+		for i, column := range userCSVColumns {
+			record[i] = column.header
+		}
+		//A exportação para no primeiro erro de escrita: o cliente desconectou ou a conexão falhou.
+		written := 0
+		err := writer.Write(record)
+		if err == nil {
+			err = store.Stream(ctx, query, func(user models.User) error {
+				for i, column := range userCSVColumns {
+					record[i] = escapeCSVCell(column.get(user))
+				}
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+				written++
+				//Envia os dados periodicamente. Se o cliente desconectou, a escrita falha e a exportação é interrompida.
+				if written%streamFlushEvery == 0 {
+					writer.Flush()
+					if err := writer.Error(); err != nil {
+						return err
+					}
+					return w.Flush()
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			writer.Flush()
+			if err = writer.Error(); err == nil {
+				err = w.Flush()
+			}
+		}
+		if err != nil {
+			//Os cabeçalhos e parte do corpo já foram enviados, então não há como responder com um erro HTTP.
+			slog.ErrorContext(parent, "csv export failed", "error", err, "written", written)
+		}
+	})
+	return nil
+}
+
+//Define uma função que importa usuários de um arquivo CSV.
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) ImportUsersCSV(c *fiber.Ctx) error {
+	//Cria um contexto com o tempo limite de importação configurado, já que o arquivo pode ter muitas linhas.
+	ctx, cancel := context.WithTimeout(c.UserContext(), uc.timeouts.Import)
+	defer cancel()
+
+	//dryRun=true valida todas as linhas sem gravar nada.
+	dryRun := false
+	if rawDryRun := c.Query("dryRun"); rawDryRun != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(rawDryRun); err != nil {
+			return problems.BadRequest("dryRun must be true or false")
+		}
+	}
+
+	//O CSV pode vir como upload multipart (campo "file") ou diretamente no corpo (Content-Type text/csv).
+	body, err := csvUpload(c)
+	if err != nil {
+		return problems.BadRequest(err.Error())
+	}
+	defer body.Close()
+
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	//O número de colunas é conferido em csvUser, para que linhas com colunas a mais ou a menos virem erros da própria linha.
+	reader.FieldsPerRecord = -1
+
+	//Lê o cabeçalho e mapeia cada coluna para um campo de models.User.
+	header, err := reader.Read()
+	if err != nil {
+		return problems.BadRequest("could not read CSV header: " + err.Error())
+	}
+	setters, err := csvSetters(header)
+	if err != nil {
+		return problems.BadRequest(err.Error())
+	}
+
+	//Converte e valida cada linha com o mesmo validate usado em CreateUser.
+	//Linhas válidas são gravadas em lotes de maxBulkOperations, usando o BulkWrite do store.
+	rows := []csvRowResponse{}
+	var batch []stores.BulkOperation
+	var batchRows []int
+	imported := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := uc.store.BulkWrite(ctx, batch, false)
+		if err != nil {
+			return err
+		}
+		for j, result := range results {
+			row := &rows[batchRows[j]]
+			if result.Err != nil {
+				row.Status, row.Error = storeErrorStatus(c, result.Err)
+				continue
+			}
+			imported++
+			row.Status = http.StatusCreated
+			uc.recordAudit(ctx, c, models.AuditCreate, nil, result.After)
+		}
+		batch, batchRows = nil, nil
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		//Erros de sintaxe (ex.: aspas não fechadas) impedem a leitura do resto do arquivo:
+		//a linha é reportada e a importação continua só com as linhas lidas até ali.
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, csvRowResponse{Line: parseErr.StartLine, Status: http.StatusBadRequest, Error: parseErr.Error()})
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		row := csvRowResponse{Line: line}
+		user, err := csvUser(record, err, setters)
+		if err == nil {
+			err = validate.Struct(&user)
+		}
+		if err != nil {
+			row.Status = http.StatusBadRequest
+			row.Error, row.Errors = itemErrors(c, err, "")
+			rows = append(rows, row)
+			continue
+		}
+
+		//Assim como em CreateUser, o ID é gerado pelo servidor.
+		user.Id = primitive.NewObjectID()
+		row.Id = user.Id.Hex()
+		row.Status = http.StatusOK
+		rows = append(rows, row)
+		if dryRun {
+			continue
+		}
+		batch = append(batch, stores.BulkOperation{Op: stores.BulkCreate, User: user, ExpectedVersion: stores.AnyVersion})
+		batchRows = append(batchRows, len(rows)-1)
+		if len(batch) == maxBulkOperations {
+			if err := flush(); err != nil {
+				return storeError(err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return storeError(err)
+	}
+
+	//Conta as linhas que falharam (na validação ou na gravação).
+	failed := 0
+	for _, row := range rows {
+		if row.Error != "" {
+			failed++
+		}
+	}
+
+	//Retorna 200 - OK com o resultado de cada linha, mesmo quando algumas falharam.
+	//Em dry-run, as linhas válidas têm status 200 e nenhuma é gravada.
+	return c.Status(http.StatusOK).JSON(responses.UserResponse{Status: http.StatusOK, Message: "success", Data: &fiber.Map{
+		"data":     rows,
+		"dryRun":   dryRun,
+		"imported": imported,
+		"failed":   failed,
+	}})
+}
+
+//Retorna o conteúdo CSV enviado na requisição: o campo "file" de um upload multipart ou o próprio corpo.
+func csvUpload(c *fiber.Ctx) (io.ReadCloser, error) {
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New(`multipart upload must have a "file" field`)
+		}
+		return fileHeader.Open()
+	}
+	if len(c.Body()) == 0 {
+		return nil, errors.New("request body must contain a CSV file")
+	}
+	return io.NopCloser(bytes.NewReader(c.Body())), nil
+}
+
+//Mapeia as colunas do cabeçalho para as funções que preenchem models.User.
+//Colunas desconhecidas ou repetidas são rejeitadas e as colunas name, location e title são obrigatórias.
+func csvSetters(header []string) ([]func(*models.User, string), error) {
+	setters := make([]func(*models.User, string), len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		//Os nomes das colunas não diferenciam maiúsculas (ex.: "Name" ou "deletedat").
+		matched := ""
+		for _, column := range userCSVColumns {
+			if strings.EqualFold(column.header, name) {
+				setters[i], matched = column.set, column.header
+			}
+		}
+		if matched == "" {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if seen[matched] {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		seen[matched] = true
+	}
+	for _, column := range userCSVColumns {
+		if column.set != nil && !seen[column.header] {
+			return nil, fmt.Errorf("missing CSV column %q", column.header)
+		}
+	}
+	return setters, nil
+}
+
+//Converte uma linha do CSV em models.User. readErr é o erro retornado pelo csv.Reader para a linha.
+func csvUser(record []string, readErr error, setters []func(*models.User, string)) (models.User, error) {
+	if readErr != nil {
+		return models.User{}, readErr
+	}
+	if len(record) != len(setters) {
+		return models.User{}, fmt.Errorf("expected %d columns, got %d", len(setters), len(record))
+	}
+	var user models.User
+	for i, value := range record {
+		if setters[i] != nil {
+			setters[i](&user, unescapeCSVCell(strings.TrimSpace(value)))
+		}
+	}
+	return user, nil
+}
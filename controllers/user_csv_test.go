@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//Lê o CSV exportado e retorna as linhas, incluindo o cabeçalho.
+func exportedCSV(t *testing.T, resp testResponse) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(resp.raw)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v\n%s", err, resp.raw)
+	}
+	return records
+}
+
+func TestExportUsersCSVEscapesFormulas(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "=cmd|' /C calc'!A0", "@SUM(1+1)", "-2+3")
+
+	records := exportedCSV(t, doRequest(t, app, "GET", "/users/export.csv", "").expect(t, http.StatusOK))
+	want := [][]string{
+		{"id", "name", "location", "title", "version", "deletedAt"},
+		{user.Id.Hex(), "'=cmd|' /C calc'!A0", "'@SUM(1+1)", "'-2+3", "1", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("exported %q, want %q", records, want)
+	}
+}
+
+func TestExportUsersCSVIncludesDeletedAt(t *testing.T) {
+	app, store := newTestUserApp(t)
+	active := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	deleted := createTestUser(t, store, "Bruno", "Porto", "Manager")
+	if _, err := store.Delete(context.Background(), deleted.Id, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	records := exportedCSV(t, doRequest(t, app, "GET", "/users/export.csv?includeDeleted=true", "").expect(t, http.StatusOK))
+	if len(records) != 3 {
+		t.Fatalf("exported %d rows, want a header and 2 users", len(records))
+	}
+	for _, record := range records[1:] {
+		switch record[0] {
+		case active.Id.Hex():
+			if record[5] != "" {
+				t.Errorf("active user has deletedAt %q", record[5])
+			}
+		case deleted.Id.Hex():
+			if record[5] == "" {
+				t.Error("deleted user has no deletedAt")
+			}
+		}
+	}
+}
+
+func TestImportUsersCSVRoundTrip(t *testing.T) {
+	app, store := newTestUserApp(t)
+	createTestUser(t, store, "=HYPERLINK(\"http://example.com\")", "Lisbon", "Engineer")
+	exported := doRequest(t, app, "GET", "/users/export.csv", "").expect(t, http.StatusOK).raw
+
+	//O arquivo exportado pode ser importado de novo: id, version e deletedAt são ignorados e o apóstrofo é removido.
+	resp := doRequest(t, app, "POST", "/users/import", exported, "Content-Type", "text/csv").expect(t, http.StatusOK)
+	if failed := resp.body["data"].(map[string]interface{})["failed"]; failed != 0.0 {
+		t.Fatalf("import failed: %s", resp.raw)
+	}
+	names := map[string]int{}
+	for _, user := range doRequest(t, app, "GET", "/users", "").expect(t, http.StatusOK).data(t).([]interface{}) {
+		names[user.(map[string]interface{})["name"].(string)]++
+	}
+	if names[`=HYPERLINK("http://example.com")`] != 2 {
+		t.Errorf("names after the import = %v", names)
+	}
+}
+
+func TestImportUsersCSVReportsRowErrors(t *testing.T) {
+	app, _ := newTestUserApp(t)
+	body := "name,location,title\nAna,Lisbon,Engineer\nBruno,,Manager\nCarla,Porto\n"
+
+	resp := doRequest(t, app, "POST", "/users/import?dryRun=true", body, "Content-Type", "text/csv").expect(t, http.StatusOK)
+	var statuses []int
+	for _, row := range resp.data(t).([]interface{}) {
+		statuses = append(statuses, int(row.(map[string]interface{})["status"].(float64)))
+	}
+	if want := []int{http.StatusOK, http.StatusBadRequest, http.StatusBadRequest}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	//Em dry-run nada é gravado.
+	if users := doRequest(t, app, "GET", "/users", "").expect(t, http.StatusOK).data(t).([]interface{}); len(users) != 0 {
+		t.Errorf("dry-run stored %d users", len(users))
+	}
+}
+
+func TestCSVCellEscaping(t *testing.T) {
+	tests := map[string]string{
+		"Ana":      "Ana",
+		"":         "",
+		"=1+1":     "'=1+1",
+		"+1":       "'+1",
+		"-1":       "'-1",
+		"@A1":      "'@A1",
+		"\t=1":     "'\t=1",
+		"'quoted'": "'quoted'",
+		"a=b":      "a=b",
+	}
+	for value, want := range tests {
+		escaped := escapeCSVCell(value)
+		if escaped != want {
+			t.Errorf("escapeCSVCell(%q) = %q, want %q", value, escaped, want)
+		}
+		if unescaped := unescapeCSVCell(escaped); unescaped != value {
+			t.Errorf("unescapeCSVCell(%q) = %q, want %q", escaped, unescaped, value)
+		}
+	}
+}
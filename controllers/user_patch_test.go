@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	testMergePatch = "application/merge-patch+json"
+	testJSONPatch  = "application/json-patch+json"
+)
+
+func TestPatchAUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        map[string]interface{}
+	}{
+		{"merge patch", testMergePatch, `{"title":"Manager"}`, map[string]interface{}{"name": "Ana", "location": "Lisbon", "title": "Manager"}},
+		{"merge patch with parameters", testMergePatch + "; charset=utf-8", `{"name":"Ana Maria"}`, map[string]interface{}{"name": "Ana Maria", "location": "Lisbon", "title": "Engineer"}},
+		{"json patch", testJSONPatch, `[{"op":"replace","path":"/location","value":"Porto"},{"op":"test","path":"/name","value":"Ana"}]`, map[string]interface{}{"name": "Ana", "location": "Porto", "title": "Engineer"}},
+		//A versão é do servidor: o valor enviado no patch é ignorado.
+		{"version is ignored", testMergePatch, `{"title":"Manager","version":99}`, map[string]interface{}{"title": "Manager", "version": 2.0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, store := newTestUserApp(t)
+			user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+
+			resp := doRequest(t, app, "PATCH", "/user/"+user.Id.Hex(), test.body, fiber.HeaderContentType, test.contentType).expect(t, http.StatusOK)
+			got := resp.user(t)
+			for field, want := range test.want {
+				if got[field] != want {
+					t.Errorf("%s = %v, want %v", field, got[field], want)
+				}
+			}
+			if etag := resp.header.Get(fiber.HeaderETag); etag != `"2"` {
+				t.Errorf("ETag = %q, want \"2\"", etag)
+			}
+		})
+	}
+}
+
+func TestPatchAUserRejectsInvalidPatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		problemType string
+	}{
+		{"plain json", fiber.MIMEApplicationJSON, `{"title":"Manager"}`, http.StatusUnsupportedMediaType, problems.TypeUnsupportedMediaType},
+		{"no content type", "", `{"title":"Manager"}`, http.StatusUnsupportedMediaType, problems.TypeUnsupportedMediaType},
+		{"malformed merge patch", testMergePatch, `{"title":`, http.StatusBadRequest, problems.TypeBadRequest},
+		{"json patch as object", testJSONPatch, `{"title":"Manager"}`, http.StatusBadRequest, problems.TypeBadRequest},
+		{"failed test op", testJSONPatch, `[{"op":"test","path":"/name","value":"Bruno"},{"op":"replace","path":"/title","value":"Manager"}]`, http.StatusBadRequest, problems.TypeBadRequest},
+		{"missing path", testJSONPatch, `[{"op":"replace","path":"/nickname","value":"Aninha"}]`, http.StatusBadRequest, problems.TypeBadRequest},
+		{"merge patch changes id", testMergePatch, `{"id":"` + primitive.NewObjectID().Hex() + `"}`, http.StatusBadRequest, problems.TypeValidation},
+		{"json patch changes id", testJSONPatch, `[{"op":"replace","path":"/id","value":"` + primitive.NewObjectID().Hex() + `"}]`, http.StatusBadRequest, problems.TypeValidation},
+		{"merge patch removes a required field", testMergePatch, `{"title":null}`, http.StatusBadRequest, problems.TypeValidation},
+		{"json patch empties a required field", testJSONPatch, `[{"op":"replace","path":"/name","value":""}]`, http.StatusBadRequest, problems.TypeValidation},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, store := newTestUserApp(t)
+			user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+
+			doRequest(t, app, "PATCH", "/user/"+user.Id.Hex(), test.body, fiber.HeaderContentType, test.contentType).
+				expectProblem(t, test.status, test.problemType)
+			//Um patch rejeitado não altera o usuário gravado.
+			stored, err := store.Get(context.Background(), user.Id, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored != user {
+				t.Errorf("stored user = %+v, want %+v", stored, user)
+			}
+		})
+	}
+}
+
+func TestPatchAUserPreconditions(t *testing.T) {
+	app, store := newTestUserApp(t)
+	user := createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	target := "/user/" + user.Id.Hex()
+
+	doRequest(t, app, "PATCH", target, `{"title":"Manager"}`, fiber.HeaderContentType, testMergePatch, fiber.HeaderIfMatch, `"2"`).
+		expectProblem(t, http.StatusPreconditionFailed, problems.TypePreconditionFailed)
+	doRequest(t, app, "PATCH", target, `{"title":"Manager"}`, fiber.HeaderContentType, testMergePatch, fiber.HeaderIfMatch, `"1"`).expect(t, http.StatusOK)
+	doRequest(t, app, "PATCH", "/user/"+primitive.NewObjectID().Hex(), `{"title":"Manager"}`, fiber.HeaderContentType, testMergePatch).
+		expectProblem(t, http.StatusNotFound, problems.TypeNotFound)
+	doRequest(t, app, "PATCH", "/user/not-an-id", `{"title":"Manager"}`, fiber.HeaderContentType, testMergePatch).
+		expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+)
+
+//Parâmetros da query string de GET /users que não são filtros.
+var listParams = map[string]bool{"limit": true, "cursor": true, "sort": true, "includeDeleted": true}
+
+//Lê a query string de GET /users e monta a consulta do store.
+//
+//includeDeleted=true inclui os usuários excluídos logicamente.
+//Filtros usam o formato campo=valor (igualdade) ou campo[op]=valor, com op entre eq, ne, in (valores separados por vírgula) e prefix.
+//A ordenação usa sort=campo ou sort=-campo (decrescente).
+//Ex.: /users?location=Lisbon&title[in]=Engineer,Manager&sort=-name
+//
+//Campos e operadores fora da lista permitida retornam um erro que envolve stores.ErrInvalidQuery.
+func parseUserQuery(c *fiber.Ctx) (stores.UserQuery, error) {
+	query, err := parseUserStreamQuery(c)
+	if err != nil {
+		return stores.UserQuery{}, err
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		return stores.UserQuery{}, err
+	}
+	query.Limit = limit
+	return query, nil
+}
+
+//Lê a query string como parseUserQuery, mas sem o parâmetro limit, que GET /users/stream ignora.
+func parseUserStreamQuery(c *fiber.Ctx) (stores.UserQuery, error) {
+	query := stores.UserQuery{Cursor: c.Query("cursor")}
+
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		return stores.UserQuery{}, err
+	}
+	query.IncludeDeleted = includeDeleted
+
+	//sort: um único campo, com "-" na frente para ordem decrescente.
+	if rawSort := c.Query("sort"); rawSort != "" {
+		field, descending := strings.CutPrefix(rawSort, "-")
+		if field != "id" && !stores.IsUserField(field) {
+			return stores.UserQuery{}, fmt.Errorf("%w: unknown sort field %q", stores.ErrInvalidQuery, field)
+		}
+		if field == "id" {
+			field = ""
+		}
+		query.Sort = stores.UserSort{Field: field, Descending: descending}
+	}
+
+	//Os demais parâmetros são filtros. VisitAll percorre também parâmetros repetidos (?title=a&title[ne]=b).
+	var parseErr error
+	c.Context().QueryArgs().VisitAll(func(rawKey, rawValue []byte) {
+		key := string(rawKey)
+		if parseErr != nil || listParams[key] {
+			return
+		}
+		filter, err := parseUserFilter(key, string(rawValue))
+		if err != nil {
+			parseErr = err
+			return
+		}
+		query.Filters = append(query.Filters, filter)
+	})
+	if parseErr != nil {
+		return stores.UserQuery{}, parseErr
+	}
+	return query, nil
+}
+
+//Lê o parâmetro includeDeleted, que inclui usuários excluídos logicamente em GetAUser e GetAllUsers.
+func parseIncludeDeleted(c *fiber.Ctx) (bool, error) {
+	rawValue := c.Query("includeDeleted")
+	if rawValue == "" {
+		return false, nil
+	}
+	includeDeleted, err := strconv.ParseBool(rawValue)
+	if err != nil {
+		return false, fmt.Errorf("%w: includeDeleted must be true or false", stores.ErrInvalidQuery)
+	}
+	return includeDeleted, nil
+}
+
+//Lê o parâmetro limit (tamanho da página). Sem o parâmetro, usa stores.DefaultPageSize;
+//valores acima de stores.MaxPageSize são reduzidos pelo servidor.
+func parseLimit(c *fiber.Ctx) (int, error) {
+	rawLimit := c.Query("limit")
+	if rawLimit == "" {
+		return stores.DefaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("%w: limit must be a positive integer", stores.ErrInvalidQuery)
+	}
+	return min(limit, stores.MaxPageSize), nil
+}
+
+//Converte um parâmetro campo=valor ou campo[op]=valor em um stores.UserFilter.
+func parseUserFilter(key, value string) (stores.UserFilter, error) {
+	field, op := key, stores.FilterEq
+	if open := strings.IndexByte(key, '['); open >= 0 {
+		if !strings.HasSuffix(key, "]") {
+			return stores.UserFilter{}, fmt.Errorf("%w: malformed parameter %q", stores.ErrInvalidQuery, key)
+		}
+		field, op = key[:open], stores.FilterOp(key[open+1:len(key)-1])
+	}
+	if !stores.IsUserField(field) {
+		return stores.UserFilter{}, fmt.Errorf("%w: unknown field %q", stores.ErrInvalidQuery, field)
+	}
+	if !stores.IsFilterOp(op) {
+		return stores.UserFilter{}, fmt.Errorf("%w: unknown operator %q", stores.ErrInvalidQuery, op)
+	}
+
+	values := []string{value}
+	if op == stores.FilterIn {
+		values = strings.Split(value, ",")
+	}
+	return stores.UserFilter{Field: field, Op: op, Values: values}, nil
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+)
+
+//Executa parseUserQuery sobre a query string informada, dentro de uma requisição do Fiber.
+func parseTestQuery(t *testing.T, rawQuery string) (stores.UserQuery, error) {
+	t.Helper()
+	var query stores.UserQuery
+	var parseErr error
+	app := fiber.New()
+	app.Get("/users", func(c *fiber.Ctx) error {
+		query, parseErr = parseUserQuery(c)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("GET", "/users?"+rawQuery, nil), -1); err != nil {
+		t.Fatal(err)
+	}
+	return query, parseErr
+}
+
+func TestParseUserQuery(t *testing.T) {
+	tests := []struct {
+		rawQuery string
+		want     stores.UserQuery
+	}{
+		{"", stores.UserQuery{Limit: stores.DefaultPageSize}},
+		{"limit=5&cursor=abc&includeDeleted=true", stores.UserQuery{Limit: 5, Cursor: "abc", IncludeDeleted: true}},
+		{"limit=1000", stores.UserQuery{Limit: stores.MaxPageSize}},
+		{"location=Lisbon", stores.UserQuery{Limit: stores.DefaultPageSize, Filters: []stores.UserFilter{
+			{Field: "location", Op: stores.FilterEq, Values: []string{"Lisbon"}},
+		}}},
+		{"title[in]=Engineer,Manager&name[prefix]=An&location[ne]=Porto", stores.UserQuery{Limit: stores.DefaultPageSize, Filters: []stores.UserFilter{
+			{Field: "title", Op: stores.FilterIn, Values: []string{"Engineer", "Manager"}},
+			{Field: "name", Op: stores.FilterPrefix, Values: []string{"An"}},
+			{Field: "location", Op: stores.FilterNe, Values: []string{"Porto"}},
+		}}},
+		//Parâmetros repetidos viram condições separadas, todas obrigatórias.
+		{"title=Engineer&title[ne]=Manager", stores.UserQuery{Limit: stores.DefaultPageSize, Filters: []stores.UserFilter{
+			{Field: "title", Op: stores.FilterEq, Values: []string{"Engineer"}},
+			{Field: "title", Op: stores.FilterNe, Values: []string{"Manager"}},
+		}}},
+		{"sort=-name", stores.UserQuery{Limit: stores.DefaultPageSize, Sort: stores.UserSort{Field: "name", Descending: true}}},
+		{"sort=-id", stores.UserQuery{Limit: stores.DefaultPageSize, Sort: stores.UserSort{Descending: true}}},
+	}
+	for _, test := range tests {
+		got, err := parseTestQuery(t, test.rawQuery)
+		if err != nil {
+			t.Errorf("parseUserQuery(%q) error = %v", test.rawQuery, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseUserQuery(%q) = %+v, want %+v", test.rawQuery, got, test.want)
+		}
+	}
+}
+
+func TestParseUserQueryRejectsUnknownParameters(t *testing.T) {
+	for _, rawQuery := range []string{
+		"password=secret",
+		"id=65a1f0c2e4b0a1b2c3d4e5f6",
+		"deletedAt[ne]=null",
+		"name[regex]=.*",
+		"name[$where]=1",
+		"name[eq=Ana",
+		"sort=password",
+		"sort=-deletedAt",
+		"limit=0",
+		"limit=ten",
+		"includeDeleted=maybe",
+	} {
+		if _, err := parseTestQuery(t, rawQuery); !errors.Is(err, stores.ErrInvalidQuery) {
+			t.Errorf("parseUserQuery(%q) error = %v, want ErrInvalidQuery", rawQuery, err)
+		}
+	}
+}
+
+func TestGetAllUsersFilters(t *testing.T) {
+	app, store := newTestUserApp(t)
+	createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	createTestUser(t, store, "André", "Porto", "Engineer")
+	createTestUser(t, store, "Bruno", "Lisbon", "Manager")
+	createTestUser(t, store, "Carla", "Lisbon", ".*")
+
+	tests := map[string][]string{
+		"/users?location=Lisbon&sort=name":            {"Ana", "Bruno", "Carla"},
+		"/users?name[prefix]=An&sort=-name":           {"André", "Ana"},
+		"/users?title[in]=Engineer,Manager&sort=name": {"Ana", "André", "Bruno"},
+		"/users?location[ne]=Lisbon":                  {"André"},
+		//O prefixo é comparado literalmente, sem ser interpretado como expressão regular.
+		"/users?title[prefix]=.":  {"Carla"},
+		"/users?title[prefix]=.*": {"Carla"},
+	}
+	for target, want := range tests {
+		var got []string
+		for _, user := range doRequest(t, app, "GET", target, "").expect(t, 200).data(t).([]interface{}) {
+			got = append(got, user.(map[string]interface{})["name"].(string))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GET %s = %v, want %v", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+)
+
+//Quantidade de usuários escritos entre cada envio (flush) ao cliente em GET /users/stream.
+const streamFlushEvery = 100
+
+//Define uma função que envia todos os usuários como NDJSON (um objeto JSON por linha).
+//Parâmetro: c *fiber.Ctx representa o contexto da requisição no Fiber.
+//retorna um erro
+func (uc *UserController) StreamUsers(c *fiber.Ctx) error {
+	//Aceita os mesmos filtros, ordenação, cursor e includeDeleted de GET /users; limit é ignorado (nem é validado).
+	query, err := parseUserStreamQuery(c)
+	if err != nil {
+		return storeError(err)
+	}
+	//Depois que o corpo começa a ser enviado não é mais possível responder com 400,
+	//então a consulta e o cursor são validados antes.
+	if err := query.Validate(); err != nil {
+		return storeError(err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+
+	//O corpo é escrito depois que o handler retorna, enquanto o cursor do MongoDB é percorrido:
+	//cada usuário é codificado e descartado, então a memória não cresce com o tamanho da coleção.
+	//O contexto da requisição é guardado antes, já que c não pode ser usado depois que o handler retorna;
+	//ele não tem prazo, mas leva o trace da requisição para os comandos do cursor.
+	store, parent := uc.store, c.UserContext()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		//Não há timeout fixo, já que exportações grandes podem demorar; o contexto é cancelado
+		//quando a escrita termina ou falha, o que fecha o cursor no banco.
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		encoder := json.NewEncoder(w)
+		written := 0
+		err := store.Stream(ctx, query, func(user models.User) error {
+			if err := encoder.Encode(user); err != nil {
+				return err
+			}
+			written++
+			//Envia os dados periodicamente. Se o cliente desconectou, o flush falha e a iteração é interrompida.
+			if written%streamFlushEvery == 0 {
+				return w.Flush()
+			}
+			return nil
+		})
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			//Os cabeçalhos e parte do corpo já foram enviados, então o erro só pode ser registrado.
+			slog.ErrorContext(parent, "users stream failed", "error", err, "written", written)
+		}
+	})
+	return nil
+
+	//Consulta: Lê filtros, ordenação e cursor como em GET /users e valida antes de começar a resposta.
+	//Streaming: Os usuários são lidos do cursor do MongoDB e escritos um por linha, sem montar uma lista em memória.
+	//Desconexão: Uma falha ao enviar dados ao cliente cancela o contexto e interrompe a leitura no banco.
+}
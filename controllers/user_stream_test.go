@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Decodifica o corpo NDJSON, exigindo um objeto JSON por linha, e retorna os nomes dos usuários na ordem.
+func streamNames(t *testing.T, resp testResponse) []string {
+	t.Helper()
+	if contentType := resp.header.Get(fiber.HeaderContentType); contentType != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", contentType)
+	}
+	if resp.raw == "" {
+		return nil
+	}
+	if !strings.HasSuffix(resp.raw, "\n") {
+		t.Errorf("body does not end with a newline: %q", resp.raw)
+	}
+	var names []string
+	for i, line := range strings.Split(strings.TrimSuffix(resp.raw, "\n"), "\n") {
+		var user models.User
+		if err := json.Unmarshal([]byte(line), &user); err != nil {
+			t.Fatalf("line %d is not a JSON object: %q: %v", i+1, line, err)
+		}
+		names = append(names, user.Name)
+	}
+	return names
+}
+
+func TestStreamUsers(t *testing.T) {
+	app, store := newTestUserApp(t)
+	createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+	createTestUser(t, store, "Bruno", "Porto", "Engineer")
+	createTestUser(t, store, "Carla", "Lisbon", "Manager")
+	deleted := createTestUser(t, store, "Daniel", "Lisbon", "Engineer")
+	doRequest(t, app, "DELETE", "/user/"+deleted.Id.Hex(), "").expect(t, http.StatusOK)
+
+	//O cursor de GET /users continua o stream depois do primeiro usuário da mesma ordenação.
+	cursor, _ := doRequest(t, app, "GET", "/users?sort=name&limit=1", "").expect(t, http.StatusOK).body["data"].(map[string]interface{})["next_cursor"].(string)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/users/stream?sort=name", "Ana,Bruno,Carla"},
+		{"/users/stream?sort=-name", "Carla,Bruno,Ana"},
+		{"/users/stream?sort=name&location=Lisbon", "Ana,Carla"},
+		{"/users/stream?sort=name&title[ne]=Manager", "Ana,Bruno"},
+		{"/users/stream?sort=name&includeDeleted=true&location=Lisbon", "Ana,Carla,Daniel"},
+		{"/users/stream?sort=name&cursor=" + cursor, "Bruno,Carla"},
+		{"/users/stream?sort=name&location=Faro", ""},
+		//limit não limita o stream e nem é validado.
+		{"/users/stream?sort=name&limit=1", "Ana,Bruno,Carla"},
+		{"/users/stream?sort=name&limit=abc", "Ana,Bruno,Carla"},
+	}
+	for _, test := range tests {
+		resp := doRequest(t, app, "GET", test.target, "").expect(t, http.StatusOK)
+		if got := strings.Join(streamNames(t, resp), ","); got != test.want {
+			t.Errorf("GET %s = %s, want %s", test.target, got, test.want)
+		}
+	}
+}
+
+func TestStreamUsersRejectsInvalidQueries(t *testing.T) {
+	app, store := newTestUserApp(t)
+	createTestUser(t, store, "Ana", "Lisbon", "Engineer")
+
+	//A consulta é validada antes de o corpo começar, então os erros ainda são problemas 400.
+	for _, target := range []string{
+		"/users/stream?nickname=Ana",
+		"/users/stream?name[like]=Ana",
+		"/users/stream?sort=nickname",
+		"/users/stream?includeDeleted=yes",
+		"/users/stream?cursor=not-a-cursor",
+	} {
+		doRequest(t, app, "GET", target, "").expectProblem(t, http.StatusBadRequest, problems.TypeBadRequest)
+	}
+}
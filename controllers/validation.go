@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/pt_BR"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ptBRTranslations "github.com/go-playground/validator/v10/translations/pt_BR"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Inicializa o validador (validator/v10) para validar campos obrigatórios das requisições.
+//Os erros usam os nomes JSON dos campos (ex.: "name" em vez de "Name") e as mensagens são traduzidas
+//para o idioma do Accept-Language da requisição (ver fieldErrors).
+var validate = newValidator()
+
+//Tradutores das mensagens de validação: inglês (padrão) e português do Brasil.
+var (
+	english    ut.Translator
+	portuguese ut.Translator
+)
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	translators := ut.New(en.New(), en.New(), pt_BR.New())
+	english, _ = translators.GetTranslator("en")
+	portuguese, _ = translators.GetTranslator("pt_BR")
+	//As traduções padrão do validator cobrem todas as regras usadas nas structs (required, min, oneof...).
+	if err := enTranslations.RegisterDefaultTranslations(v, english); err != nil {
+		panic(err)
+	}
+	if err := ptBRTranslations.RegisterDefaultTranslations(v, portuguese); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+//Retorna o nome JSON do campo (a tag json até a vírgula), usado nos erros de validação.
+//Campos sem tag json usam o nome do campo Go e campos com json:"-" não aparecem nos erros.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+//FieldError é uma regra de validação que um campo não cumpriu, como aparece no membro "errors" dos problemas
+//do tipo /problems/validation e nos resultados de POST /users/bulk e POST /users/import.
+type FieldError struct {
+	//Field é o caminho JSON do campo (ex.: "name" ou "scopes[0]").
+	Field string `json:"field"`
+	//Rule é a regra da tag validate que falhou (ex.: "required" ou "oneof").
+	Rule string `json:"rule"`
+	//Message descreve o erro no idioma pedido no Accept-Language.
+	Message string `json:"message"`
+}
+
+//Escolhe o tradutor pelo cabeçalho Accept-Language: português para pt (incluindo pt-BR) e inglês nos demais casos.
+func requestTranslator(c *fiber.Ctx) ut.Translator {
+	if c.AcceptsLanguages("en", "pt") == "pt" {
+		return portuguese
+	}
+	return english
+}
+
+//Converte os erros retornados por validate.Struct em FieldError, com as mensagens no idioma da requisição.
+//ok é false quando err não é um erro de validação de campos.
+func fieldErrors(c *fiber.Ctx, err error) (fields []FieldError, ok bool) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, false
+	}
+	translator := requestTranslator(c)
+	for _, fieldErr := range validationErrs {
+		//O namespace começa com o nome da struct validada (ex.: "User.name"), que não faz parte do JSON.
+		_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+		fields = append(fields, FieldError{Field: field, Rule: fieldErr.Tag(), Message: fieldErr.Translate(translator)})
+	}
+	return fields, true
+}
+
+//Junta as mensagens dos campos em uma só, usada como detail do problema e como erro de um item em lote.
+func fieldErrorsSummary(fields []FieldError) string {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+//Retorna a mensagem e os campos de um item inválido em POST /users/bulk ou POST /users/import.
+//Nos erros de validação, prefix é acrescentado ao caminho de cada campo (ex.: "user." no lote);
+//os demais erros (ex.: um ID inválido) só têm a mensagem.
+func itemErrors(c *fiber.Ctx, err error, prefix string) (string, []FieldError) {
+	fields, ok := fieldErrors(c, err)
+	if !ok {
+		return err.Error(), nil
+	}
+	for i := range fields {
+		fields[i].Field = prefix + fields[i].Field
+	}
+	return fieldErrorsSummary(fields), fields
+}
+
+//Converte um erro de validateBody no erro da aplicação: /problems/validation com a lista de campos no membro "errors".
+//Outros erros do validador (ex.: um valor que não é struct) são falhas internas.
+func validationProblem(c *fiber.Ctx, err error) error {
+	fields, ok := fieldErrors(c, err)
+	if !ok {
+		return problems.Internal(err)
+	}
+	return problems.Validation(fieldErrorsSummary(fields)).With("errors", fields)
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Valida value dentro de uma requisição com o Accept-Language informado e retorna os FieldError.
+func validateTestBody(t *testing.T, acceptLanguage string, value interface{}) []FieldError {
+	t.Helper()
+	var fields []FieldError
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		err := validateBody(c, value)
+		var ok bool
+		if fields, ok = fieldErrors(c, err); !ok {
+			t.Errorf("validateBody error = %v, want validation errors", err)
+		}
+		return nil
+	})
+	req := httptest.NewRequest("POST", "/", nil)
+	if acceptLanguage != "" {
+		req.Header.Set(fiber.HeaderAcceptLanguage, acceptLanguage)
+	}
+	if _, err := app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+func TestFieldErrorsLanguage(t *testing.T) {
+	english := []FieldError{
+		{Field: "name", Rule: "required", Message: "name is a required field"},
+		{Field: "location", Rule: "required", Message: "location is a required field"},
+		{Field: "title", Rule: "required", Message: "title is a required field"},
+	}
+	portuguese := []FieldError{
+		{Field: "name", Rule: "required", Message: "name é um campo obrigatório"},
+		{Field: "location", Rule: "required", Message: "location é um campo obrigatório"},
+		{Field: "title", Rule: "required", Message: "title é um campo obrigatório"},
+	}
+
+	tests := map[string][]FieldError{
+		"":                       english,
+		"en":                     english,
+		"en-US":                  english,
+		"pt-BR":                  portuguese,
+		"pt":                     portuguese,
+		"pt-PT":                  portuguese,
+		"fr-FR, pt-BR;q=0.8":     portuguese,
+		"pt-BR;q=0.5, en;q=0.9":  english,
+		"fr":                     english,
+		"pt-BR, en;q=0.9, *;q=0": portuguese,
+	}
+	for acceptLanguage, want := range tests {
+		if got := validateTestBody(t, acceptLanguage, &models.User{}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Accept-Language %q: fieldErrors = %+v, want %+v", acceptLanguage, got, want)
+		}
+	}
+}
+
+func TestFieldErrorsPaths(t *testing.T) {
+	request := &createAPIKeyRequest{Name: "ci", Scopes: []string{"users:read", "users:admin"}}
+
+	got := validateTestBody(t, "en", request)
+	want := []FieldError{{Field: "scopes[1]", Rule: "oneof", Message: "scopes[1] must be one of [users:read users:write users:delete apikeys:admin]"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fieldErrors = %+v, want %+v", got, want)
+	}
+
+	got = validateTestBody(t, "pt-BR", request)
+	want = []FieldError{{Field: "scopes[1]", Rule: "oneof", Message: "scopes[1] deve ser um de [users:read users:write users:delete apikeys:admin]"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fieldErrors = %+v, want %+v", got, want)
+	}
+
+	message, fields := "", []FieldError(nil)
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		message, fields = itemErrors(c, validate.Struct(&models.User{Name: "Ana", Location: "Lisbon"}), "user.")
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("POST", "/", nil), -1); err != nil {
+		t.Fatal(err)
+	}
+	if message != "title is a required field" || len(fields) != 1 || fields[0].Field != "user.title" {
+		t.Errorf("itemErrors = %q %+v, want the title error under user.", message, fields)
+	}
+}
+
+func TestJSONFieldName(t *testing.T) {
+	type example struct {
+		Tagged   string `json:"tagged,omitempty"`
+		Untagged string
+		Hidden   string `json:"-"`
+		Empty    string `json:",omitempty"`
+	}
+	want := map[string]string{"Tagged": "tagged", "Untagged": "Untagged", "Hidden": "", "Empty": "Empty"}
+	fields := reflect.TypeOf(example{})
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		if got := jsonFieldName(field); got != want[field.Name] {
+			t.Errorf("jsonFieldName(%s) = %q, want %q", field.Name, got, want[field.Name])
+		}
+	}
+}
+
+func TestCreateUserValidationProblemLanguage(t *testing.T) {
+	app, _ := newTestUserApp(t)
+
+	tests := map[string]string{
+		"en":    "location is a required field; title is a required field",
+		"pt-BR": "location é um campo obrigatório; title é um campo obrigatório",
+	}
+	for acceptLanguage, detail := range tests {
+		resp := doRequest(t, app, "POST", "/user", `{"name":"Ana"}`, fiber.HeaderAcceptLanguage, acceptLanguage).
+			expectProblem(t, http.StatusBadRequest, problems.TypeValidation)
+		if resp.body["detail"] != detail {
+			t.Errorf("%s: detail = %v, want %q", acceptLanguage, resp.body["detail"], detail)
+		}
+		errs, _ := resp.body["errors"].([]interface{})
+		if len(errs) != 2 || errs[0].(map[string]interface{})["field"] != "location" || errs[1].(map[string]interface{})["rule"] != "required" {
+			t.Errorf("%s: errors = %v", acceptLanguage, resp.body["errors"])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewAddsContextIDs(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(WithRequestID(context.Background(), "req-1"),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled}))
+
+	tests := map[string]string{
+		FormatJSON: `"request_id":"req-1","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"`,
+		FormatText: `request_id=req-1 trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7`,
+	}
+	for format, want := range tests {
+		var buffer bytes.Buffer
+		logger, err := New(&buffer, format, slog.LevelInfo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Os identificadores também aparecem em loggers derivados com With.
+		logger.With("component", "test").InfoContext(ctx, "hello")
+		logger.DebugContext(ctx, "hidden")
+		logger.Info("without context")
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("%s: got %d lines, want 2 (the debug line is below the level): %q", format, len(lines), buffer.String())
+		}
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("%s: line = %s, want it to contain %s", format, lines[0], want)
+		}
+		if strings.Contains(lines[1], "request_id") || strings.Contains(lines[1], "trace_id") {
+			t.Errorf("%s: line without context = %s, want no ids", format, lines[1])
+		}
+	}
+
+	if _, err := New(&bytes.Buffer{}, "xml", slog.LevelInfo); err == nil {
+		t.Error("New accepted the xml format")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{"debug": slog.LevelDebug, "info": slog.LevelInfo, "WARN": slog.LevelWarn, "error": slog.LevelError}
+	for value, want := range tests {
+		if got, err := ParseLevel(value); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel accepted verbose")
+	}
+}
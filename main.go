@@ -1,21 +1,64 @@
 package main
 
 import (
-	"github.com/gofiber/fiber/v2"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/nathanfernande/golang-mongodb-api/configs"
-	"github.com/nathanfernande/golang-mongodb-api/routes"
+	"github.com/nathanfernande/golang-mongodb-api/logging"
 )
 
 func main() {
-	//iniciando a aplicação
-	app := fiber.New()
+	//carrega a configuração (valores padrão, arquivo, variáveis de ambiente e flags) antes de qualquer conexão
+	config, err := configs.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal(err)
+	}
+	//--print-config mostra a configuração efetiva, com a senha do MongoDB oculta, e encerra
+	if config.PrintConfig {
+		if err := config.Print(os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	//os logs (incluindo os de acesso) são escritos na saída de erro no formato e a partir do nível configurados
+	level, err := logging.ParseLevel(config.Log.Level)
+	if err != nil {
+		fatal(err)
+	}
+	logger, err := logging.New(os.Stderr, config.Log.Format, level)
+	if err != nil {
+		fatal(err)
+	}
+	slog.SetDefault(logger)
 
-	// rodar o banco de dados
-	configs.ConnectDB()
+	//monta a aplicação: conecta ao MongoDB, cria os índices e registra as rotas
+	app, err := NewApp(config)
+	if err != nil {
+		fatal(err)
+	}
 
-	//rotas
-	routes.UserRoute(app)
+	//SIGINT (Ctrl+C) e SIGTERM (enviado por orquestradores como Docker e Kubernetes) iniciam o encerramento gracioso
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	//inicia o servidor HTTP no endereço configurado (:6000 por padrão) e espera até o encerramento
+	if err := app.Run(ctx); err != nil {
+		fatal(err)
+	}
+}
 
-	//inicia o servidos HTTP na porta 6000
-	app.Listen(":6000")
+//Registra o erro e encerra o programa com status 1.
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
 }
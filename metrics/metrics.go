@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+//Buckets dos comandos do MongoDB, em segundos. Começam abaixo de 1ms, já que a maioria das leituras por índice é rápida.
+var mongoBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//Metrics guarda os coletores da aplicação em um registro próprio, exposto em GET /metrics no formato de texto do Prometheus.
+//As métricas HTTP são alimentadas por middleware.Metrics e as do MongoDB pelos monitores de CommandMonitor e PoolMonitor.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	mongoCommands    *prometheus.HistogramVec
+	poolConnections  *prometheus.GaugeVec
+	poolInUse        *prometheus.GaugeVec
+	poolCheckoutFail *prometheus.CounterVec
+	poolCleared      *prometheus.CounterVec
+}
+
+//New cria os coletores e os registra, junto com as métricas do runtime do Go e do processo.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests handled, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time spent in the handler chain, by method, route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		mongoCommands: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mongodb_command_duration_seconds",
+			Help:    "MongoDB command round trips, by command name and outcome.",
+			Buckets: mongoBuckets,
+		}, []string{"command", "outcome"}),
+		poolConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongodb_pool_connections",
+			Help: "Open connections in the MongoDB connection pool, by server address.",
+		}, []string{"address"}),
+		poolInUse: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongodb_pool_connections_in_use",
+			Help: "Connections checked out of the MongoDB connection pool, by server address.",
+		}, []string{"address"}),
+		poolCheckoutFail: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mongodb_pool_checkout_failures_total",
+			Help: "Failed connection check-outs from the MongoDB connection pool, by server address and reason.",
+		}, []string{"address", "reason"}),
+		poolCleared: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mongodb_pool_cleared_total",
+			Help: "Times the MongoDB connection pool was cleared after a server error, by server address.",
+		}, []string{"address"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.mongoCommands, m.poolConnections, m.poolInUse, m.poolCheckoutFail, m.poolCleared,
+	)
+	return m
+}
+
+//Handler retorna o http.Handler que escreve as métricas no formato de texto do Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+//ObserveRequest registra uma requisição HTTP. route é o padrão da rota (ex.: "/user/:userId"), e não o caminho,
+//para que o número de séries não cresça com os IDs.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	m.httpRequests.With(labels).Inc()
+	m.httpDuration.With(labels).Observe(duration.Seconds())
+}
+
+//CommandMonitor retorna o monitor de comandos a ser usado nas opções do cliente do MongoDB (ver configs.ConnectDB).
+//Cada comando concluído entra em mongodb_command_duration_seconds, com a duração medida pelo próprio driver.
+func (m *Metrics) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			m.mongoCommands.WithLabelValues(e.CommandName, "success").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			m.mongoCommands.WithLabelValues(e.CommandName, "failure").Observe(e.Duration.Seconds())
+		},
+	}
+}
+
+//PoolMonitor retorna o monitor do pool de conexões a ser usado nas opções do cliente do MongoDB (ver configs.ConnectDB).
+func (m *Metrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				m.poolConnections.WithLabelValues(e.Address).Inc()
+			case event.ConnectionClosed:
+				m.poolConnections.WithLabelValues(e.Address).Dec()
+			case event.GetSucceeded:
+				m.poolInUse.WithLabelValues(e.Address).Inc()
+			case event.ConnectionReturned:
+				m.poolInUse.WithLabelValues(e.Address).Dec()
+			case event.GetFailed:
+				m.poolCheckoutFail.WithLabelValues(e.Address, e.Reason).Inc()
+			case event.PoolCleared:
+				m.poolCleared.WithLabelValues(e.Address).Inc()
+			}
+		},
+	}
+}
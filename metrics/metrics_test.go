@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+//Retorna as métricas de m no formato de texto do Prometheus, como em GET /metrics.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	return recorder.Body.String()
+}
+
+//Confere que cada linha de want aparece nas métricas.
+func expectMetrics(t *testing.T, scraped string, want ...string) {
+	t.Helper()
+	for _, line := range want {
+		if !strings.Contains(scraped, line+"\n") {
+			t.Errorf("metrics do not contain %s", line)
+		}
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+	m.ObserveRequest("GET", "/user/:userId", 200, 20*time.Millisecond)
+	m.ObserveRequest("GET", "/user/:userId", 200, 2*time.Second)
+	m.ObserveRequest("DELETE", "/user/:userId", 412, time.Millisecond)
+
+	expectMetrics(t, scrape(t, m),
+		`http_requests_total{method="GET",route="/user/:userId",status="200"} 2`,
+		`http_requests_total{method="DELETE",route="/user/:userId",status="412"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/user/:userId",status="200",le="0.025"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/user/:userId",status="200",le="+Inf"} 2`,
+		`http_request_duration_seconds_sum{method="GET",route="/user/:userId",status="200"} 2.02`,
+	)
+}
+
+func TestMongoMonitors(t *testing.T) {
+	m := New()
+	commands := m.CommandMonitor()
+	commands.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", Duration: 3 * time.Millisecond}})
+	commands.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", Duration: time.Millisecond}})
+	commands.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "update", Duration: time.Second}})
+
+	pool := m.PoolMonitor()
+	const address = "localhost:27017"
+	for _, eventType := range []string{
+		event.ConnectionCreated, event.ConnectionCreated, event.ConnectionClosed,
+		event.GetSucceeded, event.GetSucceeded, event.ConnectionReturned,
+		event.PoolCleared,
+	} {
+		pool.Event(&event.PoolEvent{Type: eventType, Address: address})
+	}
+	pool.Event(&event.PoolEvent{Type: event.GetFailed, Address: address, Reason: event.ReasonTimedOut})
+
+	expectMetrics(t, scrape(t, m),
+		`mongodb_command_duration_seconds_count{command="find",outcome="success"} 2`,
+		`mongodb_command_duration_seconds_bucket{command="find",outcome="success",le="0.001"} 1`,
+		`mongodb_command_duration_seconds_count{command="update",outcome="failure"} 1`,
+		`mongodb_pool_connections{address="localhost:27017"} 1`,
+		`mongodb_pool_connections_in_use{address="localhost:27017"} 1`,
+		`mongodb_pool_checkout_failures_total{address="localhost:27017",reason="timeout"} 1`,
+		`mongodb_pool_cleared_total{address="localhost:27017"} 1`,
+	)
+}
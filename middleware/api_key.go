@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/controllers"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+)
+
+//APIKeyHeader é o cabeçalho em que os serviços enviam a chave de API.
+const APIKeyHeader = "X-API-Key"
+
+//Intervalo mínimo entre duas atualizações de lastUsedAt da mesma chave, para não gravar no banco a cada requisição.
+const lastUsedInterval = time.Minute
+
+//APIKey retorna um middleware que autentica a requisição pela chave do cabeçalho X-API-Key.
+//Chaves desconhecidas, expiradas ou revogadas recebem 401 - Unauthorized. Em chaves válidas, a models.APIKey
+//fica em c.Locals (ver CurrentAPIKey), o actor da auditoria passa a ser "apikey:<id>" e o último uso é registrado.
+//timeout limita as operações no store de cada requisição.
+func APIKey(store stores.APIKeyStore, timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		secret := strings.TrimSpace(c.Get(APIKeyHeader))
+		if secret == "" {
+			return problems.Unauthorized("missing API key")
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		//A chave é procurada pelo hash: o segredo nunca é comparado nem guardado em texto puro.
+		key, err := store.GetByHash(ctx, stores.HashAPIKey(secret))
+		if errors.Is(err, stores.ErrAPIKeyNotFound) {
+			return problems.Unauthorized("invalid API key")
+		}
+		if err != nil {
+			return problems.Internal(fmt.Errorf("api key lookup: %w", err))
+		}
+		now := time.Now()
+		if key.RevokedAt != nil {
+			return problems.Unauthorized("API key revoked")
+		}
+		if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+			return problems.Unauthorized("API key expired")
+		}
+
+		//Uma falha ao registrar o uso não impede a requisição.
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+			if err := store.TouchLastUsed(ctx, key.Id, now); err != nil {
+				slog.WarnContext(ctx, "api key: failed to record last use", "keyId", key.Id.Hex(), "error", err)
+			}
+		}
+
+		c.Locals(controllers.APIKeyLocalsKey, key)
+		c.Locals(controllers.ActorLocalsKey, "apikey:"+key.Id.Hex())
+		return c.Next()
+	}
+}
+
+//Authenticate combina os dois meios de autenticação: requisições com o cabeçalho X-API-Key passam por apiKeyAuth
+//e as demais por jwtAuth (que exige Authorization: Bearer).
+func Authenticate(jwtAuth, apiKeyAuth fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get(APIKeyHeader) != "" {
+			return apiKeyAuth(c)
+		}
+		return jwtAuth(c)
+	}
+}
+
+//RequireScope retorna um middleware que exige que a chave de API da requisição tenha todos os scopes informados;
+//caso contrário, responde 403 - Forbidden. Deve vir depois de Authenticate.
+//Requisições autenticadas por JWT não têm scopes e passam direto: elas representam usuários interativos.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := CurrentAPIKey(c)
+		if !ok {
+			return c.Next()
+		}
+		for _, scope := range scopes {
+			if !key.HasScope(scope) {
+				return problems.Forbidden("API key lacks scope " + scope)
+			}
+		}
+		return c.Next()
+	}
+}
+
+//CurrentAPIKey retorna a chave de API que autenticou a requisição; ok é false em requisições autenticadas por JWT.
+func CurrentAPIKey(c *fiber.Ctx) (models.APIKey, bool) {
+	key, ok := c.Locals(controllers.APIKeyLocalsKey).(models.APIKey)
+	return key, ok
+}
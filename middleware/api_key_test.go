@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nathanfernande/golang-mongodb-api/controllers"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Grava no store uma chave com os scopes informados e retorna o segredo; change ajusta a chave antes de gravá-la.
+func createTestAPIKey(t *testing.T, store stores.APIKeyStore, scopes []string, change func(*models.APIKey)) (string, models.APIKey) {
+	t.Helper()
+	secret, prefix, err := stores.NewAPIKeySecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := models.APIKey{Id: primitive.NewObjectID(), Name: "test", Prefix: prefix, Hash: stores.HashAPIKey(secret), Scopes: scopes, CreatedAt: time.Now()}
+	if change != nil {
+		change(&key)
+	}
+	if err := store.Create(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	return secret, key
+}
+
+//Servidor com Authenticate (JWT ou chave de API) e RequireScope(users:read) que responde o actor da auditoria.
+func newAPIKeyTestApp(t *testing.T, store stores.APIKeyStore) *fiber.App {
+	t.Helper()
+	keys, err := ParseKeySet([]byte(testKeySetRaw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Get("/", Authenticate(JWT(keys), APIKey(store, time.Second)), RequireScope(models.ScopeUsersRead), func(c *fiber.Ctx) error {
+		actor, _ := c.Locals(controllers.ActorLocalsKey).(string)
+		return c.SendString(actor)
+	})
+	return app
+}
+
+//Envia GET / com os cabeçalhos informados (nome e valor alternados) e retorna o status e o corpo.
+func apiKeyRequest(t *testing.T, app *fiber.App, header ...string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestAPIKeyAuthenticates(t *testing.T) {
+	store := stores.NewMemoryAPIKeyStore()
+	app := newAPIKeyTestApp(t, store)
+	secret, key := createTestAPIKey(t, store, []string{models.ScopeUsersRead}, nil)
+
+	//A chave é encontrada pelo hash do segredo; espaços em volta do cabeçalho são ignorados.
+	for _, header := range []string{secret, " " + secret + " "} {
+		status, body := apiKeyRequest(t, app, APIKeyHeader, header)
+		if status != http.StatusOK || body != "apikey:"+key.Id.Hex() {
+			t.Errorf("X-API-Key %q: status = %d, body = %q, want 200 with the key actor", header, status, body)
+		}
+	}
+}
+
+func TestAPIKeyRejectsInvalidKeys(t *testing.T) {
+	store := stores.NewMemoryAPIKeyStore()
+	app := newAPIKeyTestApp(t, store)
+	scopes := []string{models.ScopeUsersRead}
+	revoked, _ := createTestAPIKey(t, store, scopes, func(key *models.APIKey) {
+		revokedAt := time.Now().Add(-time.Minute)
+		key.RevokedAt = &revokedAt
+	})
+	expired, _ := createTestAPIKey(t, store, scopes, func(key *models.APIKey) {
+		expiresAt := time.Now().Add(-time.Second)
+		key.ExpiresAt = &expiresAt
+	})
+	valid, _ := createTestAPIKey(t, store, scopes, func(key *models.APIKey) {
+		expiresAt := time.Now().Add(time.Hour)
+		key.ExpiresAt = &expiresAt
+	})
+
+	tests := map[string]struct {
+		secret string
+		detail string
+	}{
+		"unknown":         {"uak_unknown", "invalid API key"},
+		"revoked":         {revoked, "API key revoked"},
+		"expired":         {expired, "API key expired"},
+		"hash as the key": {stores.HashAPIKey(valid), "invalid API key"},
+	}
+	for name, test := range tests {
+		status, body := apiKeyRequest(t, app, APIKeyHeader, test.secret)
+		var problem map[string]interface{}
+		_ = json.Unmarshal([]byte(body), &problem)
+		if status != http.StatusUnauthorized || problem["type"] != problems.TypeUnauthorized || problem["detail"] != test.detail {
+			t.Errorf("%s: status = %d, body = %s, want 401 with detail %q", name, status, body, test.detail)
+		}
+	}
+
+	//Uma chave que ainda não expirou continua válida.
+	if status, _ := apiKeyRequest(t, app, APIKeyHeader, valid); status != http.StatusOK {
+		t.Errorf("key expiring in the future: status = %d, want 200", status)
+	}
+}
+
+func TestAPIKeyLastUsedThrottle(t *testing.T) {
+	store := stores.NewMemoryAPIKeyStore()
+	app := newAPIKeyTestApp(t, store)
+	scopes := []string{models.ScopeUsersRead}
+	at := func(ago time.Duration) func(*models.APIKey) {
+		return func(key *models.APIKey) {
+			lastUsedAt := time.Now().Add(-ago)
+			key.LastUsedAt = &lastUsedAt
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(*models.APIKey)
+		touched bool
+	}{
+		{"never used", nil, true},
+		{"used within the interval", at(lastUsedInterval / 2), false},
+		{"used before the interval", at(2 * lastUsedInterval), true},
+	}
+	for _, test := range tests {
+		secret, key := createTestAPIKey(t, store, scopes, test.change)
+		before := time.Now()
+		if status, _ := apiKeyRequest(t, app, APIKeyHeader, secret); status != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", test.name, status)
+		}
+		stored, err := store.GetByHash(context.Background(), key.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		touched := stored.LastUsedAt != nil && !stored.LastUsedAt.Before(before)
+		if touched != test.touched {
+			t.Errorf("%s: lastUsedAt = %v, touched = %v, want %v", test.name, stored.LastUsedAt, touched, test.touched)
+		}
+	}
+
+	//Uma segunda requisição logo depois não grava de novo.
+	secret, key := createTestAPIKey(t, store, scopes, nil)
+	apiKeyRequest(t, app, APIKeyHeader, secret)
+	first, _ := store.GetByHash(context.Background(), key.Hash)
+	apiKeyRequest(t, app, APIKeyHeader, secret)
+	if second, _ := store.GetByHash(context.Background(), key.Hash); !second.LastUsedAt.Equal(*first.LastUsedAt) {
+		t.Errorf("lastUsedAt changed from %v to %v within the interval", first.LastUsedAt, second.LastUsedAt)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	store := stores.NewMemoryAPIKeyStore()
+	app := newAPIKeyTestApp(t, store)
+	withScope, _ := createTestAPIKey(t, store, []string{models.ScopeUsersWrite, models.ScopeUsersRead}, nil)
+	withoutScope, _ := createTestAPIKey(t, store, []string{models.ScopeUsersWrite}, nil)
+
+	if status, _ := apiKeyRequest(t, app, APIKeyHeader, withScope); status != http.StatusOK {
+		t.Errorf("key with the scope: status = %d, want 200", status)
+	}
+	status, body := apiKeyRequest(t, app, APIKeyHeader, withoutScope)
+	var problem map[string]interface{}
+	_ = json.Unmarshal([]byte(body), &problem)
+	if status != http.StatusForbidden || problem["type"] != problems.TypeForbidden {
+		t.Errorf("key without the scope: status = %d, body = %s, want 403", status, body)
+	}
+
+	//Requisições autenticadas por JWT não têm scopes e passam direto.
+	token := signTestToken(t, jwt.SigningMethodHS256, testSecret, "hs", validClaims())
+	if status, body := apiKeyRequest(t, app, fiber.HeaderAuthorization, "Bearer "+token); status != http.StatusOK || body != "alice" {
+		t.Errorf("JWT request: status = %d, body = %q, want 200 as alice", status, body)
+	}
+	//Sem X-API-Key, a requisição vai para o JWT, que exige Authorization.
+	if status, _ := apiKeyRequest(t, app); status != http.StatusUnauthorized {
+		t.Errorf("no credentials: status = %d, want 401", status)
+	}
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+)
+
+//IdempotencyKeyHeader é o cabeçalho com a chave escolhida pelo cliente para identificar uma requisição e as suas repetições.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+//Tamanho máximo aceito para a chave.
+const maxIdempotencyKeyLength = 255
+
+//Por quanto tempo uma chave fica reservada enquanto a primeira requisição está em andamento.
+//Se a requisição for interrompida (ex.: queda do processo), a chave volta a ficar livre depois desse tempo.
+const idempotencyLockTimeout = time.Minute
+
+//Cabeçalhos da resposta original que são guardados e devolvidos nas repetições.
+var idempotentHeaders = []string{fiber.HeaderContentType, fiber.HeaderETag, fiber.HeaderLocation}
+
+//Idempotency retorna um middleware que torna a rota idempotente para requisições com o cabeçalho Idempotency-Key.
+//Deve vir depois de Authenticate, porque as chaves são separadas por cliente.
+//
+//A primeira resposta é guardada por ttl. Repetições com a mesma chave e o mesmo corpo recebem a resposta original
+//(com o cabeçalho Idempotent-Replayed: true) sem executar o handler de novo; com outro corpo, recebem 422 - Unprocessable Entity.
+//Enquanto a primeira requisição não termina, as repetições recebem 409 - Conflict. Respostas 5xx não são guardadas,
+//para que o cliente possa tentar de novo. Requisições sem o cabeçalho não são afetadas.
+//timeout limita cada operação no store (reservar a chave, guardar a resposta ou liberar a chave).
+func Idempotency(store stores.IdempotencyStore, ttl, timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := c.Get(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			return c.Next()
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			return problems.BadRequest("Idempotency-Key must have at most 255 characters")
+		}
+
+		now := time.Now()
+		record := stores.IdempotencyRecord{
+			Key:         rateLimitClient(c) + ":" + idempotencyKey,
+			Fingerprint: requestFingerprint(c),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyLockTimeout),
+		}
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		existing, created, err := store.Begin(ctx, record)
+		cancel()
+		if err != nil {
+			return problems.Internal(fmt.Errorf("idempotency begin: %w", err))
+		}
+
+		if !created {
+			if existing.Fingerprint != record.Fingerprint {
+				return problems.IdempotencyKeyReused("Idempotency-Key was already used with a different request")
+			}
+			if !existing.Completed {
+				return problems.Conflict("A request with this Idempotency-Key is still in progress")
+			}
+			//Devolve a resposta original sem executar o handler.
+			for name, value := range existing.Headers {
+				c.Set(name, value)
+			}
+			c.Set("Idempotent-Replayed", "true")
+			return c.Status(existing.Status).Send(existing.Body)
+		}
+
+		//Primeira requisição com a chave: executa o handler e guarda a resposta.
+		//Erros do cliente (ex.: falha de validação) são convertidos em resposta aqui, para que a repetição receba o mesmo problema.
+		if err := c.Next(); err != nil {
+			if problems.StatusOf(err) >= http.StatusInternalServerError {
+				releaseIdempotencyKey(c.UserContext(), store, record.Key, timeout)
+				return err
+			}
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				releaseIdempotencyKey(c.UserContext(), store, record.Key, timeout)
+				return err
+			}
+		}
+		status := c.Response().StatusCode()
+		if status >= http.StatusInternalServerError {
+			releaseIdempotencyKey(c.UserContext(), store, record.Key, timeout)
+			return nil
+		}
+
+		record.Completed = true
+		record.Status = status
+		record.Body = append([]byte(nil), c.Response().Body()...)
+		record.Headers = map[string]string{}
+		for _, name := range idempotentHeaders {
+			if value := c.Response().Header.Peek(name); len(value) > 0 {
+				record.Headers[name] = string(value)
+			}
+		}
+		record.ExpiresAt = time.Now().Add(ttl)
+		completeIdempotencyKey(c.UserContext(), store, record, timeout)
+		return nil
+	}
+}
+
+//Hash do método, do caminho e do corpo, usado para saber se uma repetição é a mesma requisição.
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+//Grava a resposta da primeira requisição. Como em releaseIdempotencyKey, o contexto é novo: o handler pode ter
+//consumido todo o tempo da requisição, e a resposta já produzida precisa ser guardada mesmo assim.
+func completeIdempotencyKey(requestCtx context.Context, store stores.IdempotencyStore, record stores.IdempotencyRecord, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestCtx), timeout)
+	defer cancel()
+	if err := store.Complete(ctx, record); err != nil {
+		//A resposta já foi produzida; sem o registro, uma repetição seria executada de novo.
+		slog.ErrorContext(ctx, "idempotency: failed to store response", "key", record.Key, "error", err)
+	}
+}
+
+//Libera a chave depois de uma falha, com um contexto que não é cancelado junto com o da requisição,
+//mas mantém os valores dele (ID da requisição e span) para o log e o trace.
+func releaseIdempotencyKey(requestCtx context.Context, store stores.IdempotencyStore, key string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestCtx), timeout)
+	defer cancel()
+	if err := store.Release(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "idempotency: failed to release key", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+)
+
+//Tempo limite das operações no store de idempotência nos testes.
+const idempotencyTestTimeout = 100 * time.Millisecond
+
+//Store que, como o MongoIdempotencyStore, falha quando o contexto já terminou.
+type contextCheckingIdempotencyStore struct {
+	*stores.MemoryIdempotencyStore
+}
+
+func (s contextCheckingIdempotencyStore) Complete(ctx context.Context, record stores.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryIdempotencyStore.Complete(ctx, record)
+}
+
+//Servidor com o middleware Idempotency na frente de um handler que conta as execuções.
+//O corpo escolhe a resposta: {"fail":"validation"} é um 400, {"fail":"internal"} é um 500, {"slow":true} demora mais que
+//o tempo limite do store e {"wait":true} bloqueia até wait ser fechado.
+type idempotencyTestApp struct {
+	app   *fiber.App
+	calls atomic.Int32
+	//started recebe um valor quando um handler com {"wait":true} começa a executar.
+	started chan struct{}
+	wait    chan struct{}
+}
+
+func newIdempotencyTestApp(t *testing.T) *idempotencyTestApp {
+	t.Helper()
+	test := &idempotencyTestApp{started: make(chan struct{}, 1), wait: make(chan struct{})}
+	test.app = fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	test.app.Post("/user", func(c *fiber.Ctx) error {
+		c.Locals(SubjectLocalsKey, c.Get("X-Test-Subject", "alice"))
+		return c.Next()
+	}, Idempotency(contextCheckingIdempotencyStore{stores.NewMemoryIdempotencyStore()}, time.Hour, idempotencyTestTimeout), func(c *fiber.Ctx) error {
+		calls := test.calls.Add(1)
+		var body struct {
+			Fail string `json:"fail"`
+			Slow bool   `json:"slow"`
+			Wait bool   `json:"wait"`
+		}
+		_ = json.Unmarshal(c.Body(), &body)
+		switch {
+		case body.Fail == "validation":
+			return problems.Validation("name is required")
+		case body.Fail == "internal":
+			return errors.New("database down")
+		case body.Slow:
+			time.Sleep(2 * idempotencyTestTimeout)
+		case body.Wait:
+			test.started <- struct{}{}
+			<-test.wait
+		}
+		c.Set(fiber.HeaderETag, `"1"`)
+		c.Set(fiber.HeaderLocation, "/user/"+strconv.Itoa(int(calls)))
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"call": calls})
+	})
+	return test
+}
+
+func (test *idempotencyTestApp) post(t *testing.T, key, body string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/user", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := test.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp, string(raw)
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	test := newIdempotencyTestApp(t)
+
+	first, firstBody := test.post(t, "key-1", `{"name":"Ana"}`)
+	if first.StatusCode != http.StatusCreated || first.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first response = %d %v", first.StatusCode, first.Header)
+	}
+
+	replay, replayBody := test.post(t, "key-1", `{"name":"Ana"}`)
+	if replay.StatusCode != http.StatusCreated || replayBody != firstBody {
+		t.Errorf("replay = %d %s, want %d %s", replay.StatusCode, replayBody, first.StatusCode, firstBody)
+	}
+	if replay.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("replay has no Idempotent-Replayed header")
+	}
+	for _, name := range idempotentHeaders {
+		if replay.Header.Get(name) != first.Header.Get(name) {
+			t.Errorf("replayed %s = %q, want %q", name, replay.Header.Get(name), first.Header.Get(name))
+		}
+	}
+	if calls := test.calls.Load(); calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+
+	//Outra chave, outro cliente com a mesma chave ou uma requisição sem chave executam o handler de novo.
+	test.post(t, "key-2", `{"name":"Ana"}`)
+	test.post(t, "key-1", `{"name":"Ana"}`, "X-Test-Subject", "bob")
+	test.post(t, "", `{"name":"Ana"}`)
+	if calls := test.calls.Load(); calls != 4 {
+		t.Errorf("handler ran %d times, want 4", calls)
+	}
+}
+
+func TestIdempotencyStoresSlowResponses(t *testing.T) {
+	test := newIdempotencyTestApp(t)
+
+	//A resposta é guardada mesmo quando o handler leva mais que o tempo limite de uma operação no store.
+	test.post(t, "key-1", `{"slow":true}`)
+	if resp, _ := test.post(t, "key-1", `{"slow":true}`); resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("repetition of a slow request = %d %v, want a replay", resp.StatusCode, resp.Header)
+	}
+	if calls := test.calls.Load(); calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	test := newIdempotencyTestApp(t)
+	test.post(t, "key-1", `{"name":"Ana"}`)
+
+	resp, body := test.post(t, "key-1", `{"name":"Bruno"}`)
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(body, problems.TypeIdempotencyKeyReused) {
+		t.Errorf("reused key = %d %s, want 422 %s", resp.StatusCode, body, problems.TypeIdempotencyKeyReused)
+	}
+	if calls := test.calls.Load(); calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyConflictsWhileInProgress(t *testing.T) {
+	test := newIdempotencyTestApp(t)
+
+	done := make(chan int)
+	go func() {
+		req := httptest.NewRequest("POST", "/user", strings.NewReader(`{"wait":true}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		resp, err := test.app.Test(req, -1)
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	<-test.started
+
+	resp, body := test.post(t, "key-1", `{"wait":true}`)
+	if resp.StatusCode != http.StatusConflict || !strings.Contains(body, problems.TypeConflict) {
+		t.Errorf("request in progress = %d %s, want 409 %s", resp.StatusCode, body, problems.TypeConflict)
+	}
+
+	close(test.wait)
+	if status := <-done; status != http.StatusCreated {
+		t.Fatalf("first request = %d, want 201", status)
+	}
+	if resp, _ := test.post(t, "key-1", `{"wait":true}`); resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("request after the first one finished = %d, want a replay", resp.StatusCode)
+	}
+}
+
+func TestIdempotencyStoresClientErrorsOnly(t *testing.T) {
+	test := newIdempotencyTestApp(t)
+
+	//Um 4xx é guardado: a repetição recebe o mesmo problema sem executar o handler.
+	first, firstBody := test.post(t, "key-1", `{"fail":"validation"}`)
+	replay, replayBody := test.post(t, "key-1", `{"fail":"validation"}`)
+	if first.StatusCode != http.StatusBadRequest || replay.StatusCode != http.StatusBadRequest || replayBody != firstBody {
+		t.Errorf("validation error = %d %s, replay = %d %s", first.StatusCode, firstBody, replay.StatusCode, replayBody)
+	}
+	if replay.Header.Get(fiber.HeaderContentType) != problems.ContentType {
+		t.Errorf("replayed Content-Type = %q", replay.Header.Get(fiber.HeaderContentType))
+	}
+
+	//Um 5xx libera a chave para que o cliente tente de novo.
+	for i := 0; i < 2; i++ {
+		if resp, _ := test.post(t, "key-2", `{"fail":"internal"}`); resp.StatusCode != http.StatusInternalServerError || resp.Header.Get("Idempotent-Replayed") != "" {
+			t.Errorf("attempt %d = %d %v, want a new 500", i+1, resp.StatusCode, resp.Header)
+		}
+	}
+	if calls := test.calls.Load(); calls != 3 {
+		t.Errorf("handler ran %d times, want 3", calls)
+	}
+}
+
+func TestIdempotencyRejectsLongKey(t *testing.T) {
+	test := newIdempotencyTestApp(t)
+	if resp, _ := test.post(t, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+	if calls := test.calls.Load(); calls != 0 {
+		t.Errorf("handler ran %d times, want 0", calls)
+	}
+}
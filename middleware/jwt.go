@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nathanfernande/golang-mongodb-api/controllers"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Chaves de c.Locals preenchidas pelo middleware JWT depois que o token é verificado.
+const (
+	//SubjectLocalsKey guarda o subject (claim "sub") do token, como string.
+	SubjectLocalsKey = "jwtSubject"
+	//ClaimsLocalsKey guarda todas as claims do token, como jwt.MapClaims.
+	ClaimsLocalsKey = "jwtClaims"
+)
+
+//Algoritmos aceitos nos tokens. Qualquer outro (incluindo "none") é rejeitado.
+var jwtAlgorithms = []string{"HS256", "RS256"}
+
+//KeySet é o conjunto de chaves usadas para verificar os tokens JWT, indexadas pelo kid (key ID).
+//Chaves HS256 são segredos compartilhados; chaves RS256 são chaves públicas RSA.
+type KeySet struct {
+	keys map[string]verificationKey
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+//Chave no formato JWK (RFC 7517). Só os campos usados por HS256 (kty "oct") e RS256 (kty "RSA") são lidos.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+//LoadKeySet lê um arquivo JWKS (JSON no formato {"keys": [...]}) com as chaves de verificação.
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(data)
+}
+
+//ParseKeySet converte um JWKS em KeySet.
+//
+//	{"keys": [
+//	  {"kty": "oct", "kid": "app", "alg": "HS256", "k": "<segredo em base64url>"},
+//	  {"kty": "RSA", "kid": "idp", "alg": "RS256", "n": "<módulo em base64url>", "e": "AQAB"}
+//	]}
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, errors.New("invalid JWKS: no keys")
+	}
+
+	keys := map[string]verificationKey{}
+	for i, jwk := range jwks.Keys {
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %d: %w", i, err)
+		}
+		if _, ok := keys[jwk.Kid]; ok {
+			return nil, fmt.Errorf("invalid JWKS key %d: duplicate kid %q", i, jwk.Kid)
+		}
+		keys[jwk.Kid] = key
+	}
+	return &KeySet{keys: keys}, nil
+}
+
+func parseJSONWebKey(jwk jsonWebKey) (verificationKey, error) {
+	switch jwk.Kty {
+	case "oct":
+		if jwk.Alg != "" && jwk.Alg != "HS256" {
+			return verificationKey{}, fmt.Errorf("unsupported alg %q for kty oct", jwk.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.K, "="))
+		if err != nil || len(secret) == 0 {
+			return verificationKey{}, errors.New("invalid k")
+		}
+		return verificationKey{alg: "HS256", key: secret}, nil
+	case "RSA":
+		if jwk.Alg != "" && jwk.Alg != "RS256" {
+			return verificationKey{}, fmt.Errorf("unsupported alg %q for kty RSA", jwk.Alg)
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.N, "="))
+		e, errE := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.E, "="))
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return verificationKey{}, errors.New("invalid n or e")
+		}
+		publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return verificationKey{alg: "RS256", key: publicKey}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported kty %q", jwk.Kty)
+	}
+}
+
+//Escolhe a chave que verifica o token. Com kid no cabeçalho, usa apenas a chave com esse kid
+//(e exige que o algoritmo seja o da chave); sem kid, tenta todas as chaves do algoritmo do token.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, found := ks.keys[kid]
+		if !found || key.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return key.key, nil
+	}
+
+	candidates := jwt.VerificationKeySet{}
+	for _, key := range ks.keys {
+		if key.alg == token.Method.Alg() {
+			candidates.Keys = append(candidates.Keys, key.key)
+		}
+	}
+	if len(candidates.Keys) == 0 {
+		return nil, fmt.Errorf("no key for alg %s", token.Method.Alg())
+	}
+	return candidates, nil
+}
+
+//JWT retorna um middleware que exige um token "Authorization: Bearer <jwt>" assinado por uma das chaves do KeySet.
+//Tokens ausentes, inválidos, sem exp ou expirados recebem 401 - Unauthorized.
+//Em tokens válidos, o subject e as claims ficam em c.Locals (ver Subject e Claims) e o subject
+//é registrado como actor da trilha de auditoria.
+func JWT(keys *KeySet) fiber.Handler {
+	parser := jwt.NewParser(jwt.WithValidMethods(jwtAlgorithms), jwt.WithExpirationRequired())
+
+	return func(c *fiber.Ctx) error {
+		scheme, rawToken, found := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || rawToken == "" {
+			//Sem token, o cabeçalho WWW-Authenticate não leva código de erro (RFC 6750, seção 3.1).
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return problems.Unauthorized("missing bearer token")
+		}
+
+		claims := jwt.MapClaims{}
+		if _, err := parser.ParseWithClaims(strings.TrimSpace(rawToken), claims, keys.keyFunc); err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				return invalidToken(c, "token expired")
+			}
+			return invalidToken(c, "invalid token")
+		}
+		subject, err := claims.GetSubject()
+		if err != nil || subject == "" {
+			return invalidToken(c, "token has no subject")
+		}
+
+		c.Locals(SubjectLocalsKey, subject)
+		c.Locals(ClaimsLocalsKey, claims)
+		c.Locals(controllers.ActorLocalsKey, subject)
+		return c.Next()
+	}
+}
+
+//Subject retorna o subject do token verificado pelo middleware JWT, ou "" se a requisição não foi autenticada por JWT.
+func Subject(c *fiber.Ctx) string {
+	subject, _ := c.Locals(SubjectLocalsKey).(string)
+	return subject
+}
+
+//Claims retorna as claims do token verificado pelo middleware JWT, ou nil se a requisição não foi autenticada por JWT.
+func Claims(c *fiber.Ctx) jwt.MapClaims {
+	claims, _ := c.Locals(ClaimsLocalsKey).(jwt.MapClaims)
+	return claims
+}
+
+//Responde 401 - Unauthorized a um token recusado, com o cabeçalho WWW-Authenticate da RFC 6750.
+func invalidToken(c *fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token", error_description="`+message+`"`)
+	return problems.Unauthorized(message)
+}
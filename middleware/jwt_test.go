@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+var (
+	testSecret    = []byte("test-secret")
+	testRSAKey    = mustRSAKey()
+	otherRSAKey   = mustRSAKey()
+	testKeySetRaw = `{"keys":[
+		{"kty":"oct","kid":"hs","k":"` + base64.RawURLEncoding.EncodeToString(testSecret) + `"},
+		{"kty":"RSA","kid":"rs","alg":"RS256","n":"` + base64.RawURLEncoding.EncodeToString(testRSAKey.N.Bytes()) + `","e":"AQAB"}
+	]}`
+)
+
+func mustRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+//Assina um token com o método e a chave informados. kid vazio omite o cabeçalho kid.
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+//Servidor com o middleware JWT que responde o subject autenticado.
+func newJWTTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	keys, err := ParseKeySet([]byte(testKeySetRaw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Get("/", JWT(keys), func(c *fiber.Ctx) error { return c.SendString(Subject(c)) })
+	return app
+}
+
+func TestJWTAcceptsValidTokens(t *testing.T) {
+	app := newJWTTestApp(t)
+	tokens := map[string]string{
+		"HS256 with kid":    signTestToken(t, jwt.SigningMethodHS256, testSecret, "hs", validClaims()),
+		"HS256 without kid": signTestToken(t, jwt.SigningMethodHS256, testSecret, "", validClaims()),
+		"RS256 with kid":    signTestToken(t, jwt.SigningMethodRS256, testRSAKey, "rs", validClaims()),
+		"RS256 without kid": signTestToken(t, jwt.SigningMethodRS256, testRSAKey, "", validClaims()),
+	}
+	for name, token := range tokens {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestJWTRejectsInvalidTokens(t *testing.T) {
+	app := newJWTTestApp(t)
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	withoutExp := validClaims()
+	delete(withoutExp, "exp")
+	withoutSub := validClaims()
+	delete(withoutSub, "sub")
+	//Token HS256 assinado com a chave pública RSA como segredo (confusão de algoritmos).
+	publicKeyAsSecret := testRSAKey.N.Bytes()
+
+	tests := []struct {
+		name, authorization, wantChallenge string
+	}{
+		{"missing header", "", "Bearer"},
+		{"other scheme", "Basic YWxpY2U6c2VjcmV0", "Bearer"},
+		{"empty token", "Bearer ", "Bearer"},
+		{"malformed", "Bearer not.a.jwt", `invalid_token", error_description="invalid token"`},
+		{"alg none", "Bearer " + signTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()), `error_description="invalid token"`},
+		{"HS512", "Bearer " + signTestToken(t, jwt.SigningMethodHS512, testSecret, "hs", validClaims()), `error_description="invalid token"`},
+		{"wrong secret", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("other"), "hs", validClaims()), `error_description="invalid token"`},
+		{"unknown kid", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, testSecret, "other", validClaims()), `error_description="invalid token"`},
+		{"kid of another alg", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, publicKeyAsSecret, "rs", validClaims()), `error_description="invalid token"`},
+		{"RS256 from another key", "Bearer " + signTestToken(t, jwt.SigningMethodRS256, otherRSAKey, "", validClaims()), `error_description="invalid token"`},
+		{"expired", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, testSecret, "hs", expired), `error_description="token expired"`},
+		{"without exp", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, testSecret, "hs", withoutExp), `error_description="invalid token"`},
+		{"without sub", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, testSecret, "hs", withoutSub), `error_description="token has no subject"`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.authorization != "" {
+			req.Header.Set(fiber.HeaderAuthorization, test.authorization)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", test.name, resp.StatusCode)
+		}
+		if challenge := resp.Header.Get(fiber.HeaderWWWAuthenticate); !strings.Contains(challenge, test.wantChallenge) {
+			t.Errorf("%s: WWW-Authenticate = %q, want it to contain %q", test.name, challenge, test.wantChallenge)
+		}
+	}
+}
+
+func TestParseKeySetRejectsInvalidKeys(t *testing.T) {
+	tests := map[string]string{
+		"no keys":         `{"keys":[]}`,
+		"not json":        `{"keys":`,
+		"unsupported kty": `{"keys":[{"kty":"EC","kid":"ec"}]}`,
+		"oct with RS256":  `{"keys":[{"kty":"oct","kid":"hs","alg":"RS256","k":"c2VjcmV0"}]}`,
+		"RSA with HS256":  `{"keys":[{"kty":"RSA","kid":"rs","alg":"HS256","n":"AQAB","e":"AQAB"}]}`,
+		"empty secret":    `{"keys":[{"kty":"oct","kid":"hs","k":""}]}`,
+		"duplicate kid":   `{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"},{"kty":"oct","kid":"a","k":"b3RoZXI"}]}`,
+	}
+	for name, raw := range tests {
+		if _, err := ParseKeySet([]byte(raw)); err == nil {
+			t.Errorf("%s: ParseKeySet accepted %s", name, raw)
+		}
+	}
+}
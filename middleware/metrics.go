@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/nathanfernande/golang-mongodb-api/metrics"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Rótulo usado no lugar do padrão da rota quando nenhuma rota corresponde ao caminho (404).
+const unmatchedRoute = "unmatched"
+
+//Metrics retorna um middleware que registra a contagem e a latência de cada requisição em m,
+//rotuladas pelo método, pelo padrão da rota (ex.: "/user/:userId") e pelo status da resposta.
+//Deve ser registrado com app.Use antes das rotas. Em GET /users/stream e GET /users/export.csv,
+//a latência não inclui o envio do corpo, que acontece depois que o handler retorna.
+func Metrics(m *metrics.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		use := c.Route()
+		err := c.Next()
+
+		//c.Method() aponta para um buffer reaproveitado pelo Fiber, então é copiado antes de virar rótulo.
+		m.ObserveRequest(utils.CopyString(c.Method()), routePattern(c, use), responseStatus(c, err), time.Since(start))
+		return err
+	}
+}
+
+//Retorna o padrão da rota que atendeu a requisição, ou unmatchedRoute se nenhuma correspondeu.
+//use é c.Route() lido no início de um middleware registrado com app.Use: enquanto ele executa, c.Route() é a rota
+//do próprio app.Use; se continuar a mesma depois de c.Next(), nenhuma rota correspondeu ao caminho.
+func routePattern(c *fiber.Ctx, use *fiber.Route) string {
+	if c.Route() == use {
+		return unmatchedRoute
+	}
+	return c.Route().Path
+}
+
+//Retorna o status da resposta depois de c.Next(). Um erro ainda não foi convertido em resposta
+//pelo ErrorHandler, então o status vem dele (ver problems.StatusOf).
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	return problems.StatusOf(err)
+}
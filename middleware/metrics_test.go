@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/metrics"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Retorna as métricas de m no formato de texto do Prometheus, como em GET /metrics.
+func scrapeMetrics(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	return recorder.Body.String()
+}
+
+func TestMetricsRouteLabel(t *testing.T) {
+	m := metrics.New()
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Use(Metrics(m))
+	app.Get("/user/:userId", func(c *fiber.Ctx) error {
+		if c.Params("userId") == "missing" {
+			return problems.NotFound("User with specified ID not found!")
+		}
+		return c.SendString("ok")
+	})
+	app.Post("/user", func(c *fiber.Ctx) error { return c.SendStatus(http.StatusCreated) })
+
+	for _, request := range [][2]string{
+		{"GET", "/user/64b7f0c2a1b2c3d4e5f60718"},
+		{"GET", "/user/64b7f0c2a1b2c3d4e5f60719"},
+		{"GET", "/user/missing"},
+		{"POST", "/user"},
+		{"GET", "/nothing/here"},
+		{"GET", "/nothing/else"},
+	} {
+		resp, err := app.Test(httptest.NewRequest(request[0], request[1], nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	scraped := scrapeMetrics(t, m)
+	//As requisições são agrupadas pelo padrão da rota, e não pelo caminho com o ID.
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/user/:userId",status="200"} 2`,
+		`http_requests_total{method="GET",route="/user/:userId",status="404"} 1`,
+		`http_requests_total{method="POST",route="/user",status="201"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/user/:userId",status="200"} 2`,
+	} {
+		if !strings.Contains(scraped, want+"\n") {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+	for _, path := range []string{"64b7f0c2a1b2c3d4e5f60718", "/nothing/here", "missing"} {
+		if strings.Contains(scraped, path) {
+			t.Errorf("metrics use the raw path %q as a label", path)
+		}
+	}
+}
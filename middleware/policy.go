@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/patch"
+	"github.com/nathanfernande/golang-mongodb-api/policy"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Authorizer retorna uma função que cria, para cada ação, o middleware que avalia as políticas antes do controller.
+//Deve vir depois de Authenticate. Ações negadas recebem 403 - Forbidden com o nome da regra que negou.
+//
+//O usuário alvo das condições é lido do store pelo parâmetro :userId da rota (inclusive se estiver excluído, para users:restore)
+//ou, em users:create, do corpo da requisição. Em users:update as condições são avaliadas duas vezes, sobre o usuário como está
+//gravado e sobre o documento resultante (o corpo do PUT ou o patch aplicado), e as duas precisam ser permitidas:
+//assim um gerente não edita usuários de outra location nem move um usuário da própria location para outra.
+//Requisições autenticadas por chave de API não passam pelas políticas: o acesso delas é controlado pelos scopes (ver RequireScope).
+//timeout limita a leitura do usuário alvo no store.
+func Authorizer(engine *policy.Engine, store stores.UserStore, timeout time.Duration) func(action string) fiber.Handler {
+	return func(action string) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			if _, ok := CurrentAPIKey(c); ok {
+				return c.Next()
+			}
+
+			resources, err := policyResources(c, store, action, timeout)
+			if err != nil {
+				return problems.Internal(fmt.Errorf("authorization: loading resource: %w", err))
+			}
+
+			subject := policy.SubjectFromClaims(Subject(c), Claims(c))
+			for _, resource := range resources {
+				decision := engine.Evaluate(subject, action, resource)
+				if !decision.Allowed {
+					return problems.Forbidden("Action "+action+" denied by rule "+decision.Rule).With("rule", decision.Rule)
+				}
+			}
+			return c.Next()
+		}
+	}
+}
+
+//Retorna os usuários sobre os quais as políticas são avaliadas: o alvo da ação e, em users:update, também o resultado da alteração.
+//A lista tem só nil quando a ação não tem um alvo.
+func policyResources(c *fiber.Ctx, store stores.UserStore, action string, timeout time.Duration) ([]*models.User, error) {
+	if action == policy.ActionUsersCreate {
+		return []*models.User{bodyUser(c)}, nil
+	}
+
+	resource, err := policyResource(c, store, timeout)
+	if err != nil || action != policy.ActionUsersUpdate || resource == nil {
+		return []*models.User{resource}, err
+	}
+
+	//O PUT substitui o usuário pelo corpo; o PATCH aplica o patch sobre o usuário gravado, como faz o controller.
+	var updated *models.User
+	if c.Method() == fiber.MethodPatch {
+		if patched, err := patch.ApplyUser(c.Get(fiber.HeaderContentType), *resource, c.Body()); err == nil {
+			updated = &patched
+		}
+	} else {
+		updated = bodyUser(c)
+	}
+	if updated == nil {
+		//Um corpo inválido não altera nada: o controller responde 400 ou 415 se a ação for permitida sobre o usuário gravado.
+		return []*models.User{resource}, nil
+	}
+	return []*models.User{resource, updated}, nil
+}
+
+//Retorna o usuário do corpo da requisição, ou nil se o corpo for inválido (o controller responde 400).
+func bodyUser(c *fiber.Ctx) *models.User {
+	var user models.User
+	if err := c.BodyParser(&user); err != nil {
+		return nil
+	}
+	return &user
+}
+
+//Retorna o usuário indicado pelo parâmetro :userId da rota, ou nil quando a ação não tem um alvo.
+//IDs inválidos e usuários inexistentes também retornam nil: a decisão é tomada sem o alvo e,
+//se for permitida, o controller responde 400 ou 404 normalmente.
+func policyResource(c *fiber.Ctx, store stores.UserStore, timeout time.Duration) (*models.User, error) {
+	objId, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+	defer cancel()
+	user, err := store.Get(ctx, objId, true)
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/policy"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Servidor com o Authorizer sobre as políticas de configs/policy.yaml. As claims do token vêm do cabeçalho X-Test-Location,
+//no lugar do middleware JWT; o handler só responde 204 quando a ação é permitida.
+func newPolicyTestApp(t *testing.T) (*fiber.App, *stores.MemoryUserStore) {
+	t.Helper()
+	engine, err := policy.Load("../configs/policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := stores.NewMemoryUserStore()
+	authorize := Authorizer(engine, store, time.Second)
+
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Use(func(c *fiber.Ctx) error {
+		claims := jwt.MapClaims{"sub": "manager", "roles": []interface{}{"manager"}}
+		if location := c.Get("X-Test-Location"); location != "" {
+			claims["location"] = location
+		}
+		c.Locals(SubjectLocalsKey, "manager")
+		c.Locals(ClaimsLocalsKey, claims)
+		return c.Next()
+	})
+	allowed := func(c *fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) }
+	app.Post("/user", authorize(policy.ActionUsersCreate), allowed)
+	app.Put("/user/:userId", authorize(policy.ActionUsersUpdate), allowed)
+	app.Patch("/user/:userId", authorize(policy.ActionUsersUpdate), allowed)
+	return app, store
+}
+
+func TestAuthorizerUpdate(t *testing.T) {
+	app, store := newPolicyTestApp(t)
+	lisbon, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: "Ana", Location: "Lisbon", Title: "Engineer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	porto, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: "André", Location: "Porto", Title: "Engineer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		user        models.User
+		contentType string
+		body        string
+		want        int
+	}{
+		{"put in own location", "PUT", lisbon, fiber.MIMEApplicationJSON, `{"name":"Ana Maria","location":"Lisbon","title":"Manager"}`, http.StatusNoContent},
+		{"put moves to other location", "PUT", lisbon, fiber.MIMEApplicationJSON, `{"name":"Ana","location":"Porto","title":"Engineer"}`, http.StatusForbidden},
+		{"put into own location from other", "PUT", porto, fiber.MIMEApplicationJSON, `{"name":"André","location":"Lisbon","title":"Engineer"}`, http.StatusForbidden},
+		{"merge patch in own location", "PATCH", lisbon, "application/merge-patch+json", `{"title":"Manager"}`, http.StatusNoContent},
+		{"merge patch moves to other location", "PATCH", lisbon, "application/merge-patch+json", `{"location":"Porto"}`, http.StatusForbidden},
+		{"json patch moves to other location", "PATCH", lisbon, "application/json-patch+json", `[{"op":"replace","path":"/location","value":"Porto"}]`, http.StatusForbidden},
+		{"json patch in own location", "PATCH", lisbon, "application/json-patch+json", `[{"op":"replace","path":"/name","value":"Ana Maria"}]`, http.StatusNoContent},
+		{"patch of other location", "PATCH", porto, "application/merge-patch+json", `{"title":"Manager"}`, http.StatusForbidden},
+		//Um corpo inválido só é avaliado sobre o usuário gravado; o controller responde 400 ou 415.
+		{"invalid patch", "PATCH", lisbon, "application/merge-patch+json", `{"location":`, http.StatusNoContent},
+		{"unsupported patch type", "PATCH", lisbon, fiber.MIMETextPlain, `location=Porto`, http.StatusNoContent},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/user/"+test.user.Id.Hex(), strings.NewReader(test.body))
+		req.Header.Set(fiber.HeaderContentType, test.contentType)
+		req.Header.Set("X-Test-Location", "Lisbon")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("%s: status = %d, want %d: %s", test.name, resp.StatusCode, test.want, body)
+		}
+	}
+
+	//O usuário gravado não é alterado pela avaliação das políticas.
+	if stored, _ := store.Get(context.Background(), lisbon.Id, false); stored.Location != "Lisbon" || stored.Version != lisbon.Version {
+		t.Errorf("stored user = %+v", stored)
+	}
+}
+
+func TestAuthorizerMissingClaim(t *testing.T) {
+	app, _ := newPolicyTestApp(t)
+
+	tests := []struct {
+		location string
+		body     string
+		want     int
+	}{
+		{"Lisbon", `{"name":"Ana","location":"Lisbon","title":"Engineer"}`, http.StatusNoContent},
+		{"Lisbon", `{"name":"Ana","location":"Porto","title":"Engineer"}`, http.StatusForbidden},
+		//Sem a claim location, a regra deny managers-create-own-location se aplica.
+		{"", `{"name":"Ana","location":"Porto","title":"Engineer"}`, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/user", strings.NewReader(test.body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if test.location != "" {
+			req.Header.Set("X-Test-Location", test.location)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("POST /user with location claim %q and body %s: status = %d, want %d", test.location, test.body, resp.StatusCode, test.want)
+		}
+	}
+}
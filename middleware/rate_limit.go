@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+)
+
+//RateLimiter retorna um middleware que limita as requisições de cada cliente com um balde de tokens.
+//Deve vir depois de Authenticate: o cliente é a chave de API, o subject do token JWT ou, sem autenticação, o IP.
+//As requisições que não passam da autenticação são limitadas por LimitByIP.
+//Requisições GET e HEAD usam o orçamento read e as demais o orçamento write, em baldes separados.
+//
+//Toda resposta leva os cabeçalhos RateLimit-Limit, RateLimit-Remaining e RateLimit-Reset (segundos até o balde encher);
+//quando o orçamento acaba, a resposta é 429 - Too Many Requests com Retry-After.
+//Se o store falhar ou não responder em timeout, a requisição é permitida e o erro é registrado no log,
+//para que o limitador não derrube a API.
+func RateLimiter(store stores.RateLimitStore, read, write stores.RateLimit, timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit, budget := write, "write"
+		if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+			limit, budget = read, "read"
+		}
+		if err := takeRateLimit(c, store, rateLimitClient(c)+":"+budget, limit, timeout); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+//LimitByIP envolve o middleware de autenticação (ex.: Authenticate) com um balde de tokens por endereço IP,
+//consumido antes de as credenciais serem verificadas. Assim as requisições com token ou chave de API inválidos
+//também são limitadas e não chegam ao store de chaves quando o orçamento do IP acaba.
+//O orçamento deve ser maior que os de RateLimiter, que limitam cada cliente depois da autenticação.
+//O IP é o de c.IP(): atrás de um proxy, configure server.proxyHeader e server.trustedProxies para que ele seja o do cliente
+//e não o do proxy, que faria todos os clientes dividirem o mesmo orçamento.
+func LimitByIP(store stores.RateLimitStore, limit stores.RateLimit, timeout time.Duration, authenticate fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := takeRateLimit(c, store, "ip:"+c.IP()+":auth", limit, timeout); err != nil {
+			return err
+		}
+		return authenticate(c)
+	}
+}
+
+//Consome um token do balde key e escreve os cabeçalhos RateLimit-*. Retorna o erro 429 quando o orçamento acabou.
+//Se o store falhar, a requisição é permitida e o erro é registrado no log.
+func takeRateLimit(c *fiber.Ctx, store stores.RateLimitStore, key string, limit stores.RateLimit, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+	defer cancel()
+	result, err := store.Take(ctx, key, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "rate limit: store failed, allowing request", "key", key, "error", err)
+		return nil
+	}
+
+	//Os cabeçalhos de um balde consumido depois (ex.: o do cliente, após LimitByIP) substituem os anteriores.
+	c.Set("RateLimit-Limit", strconv.FormatInt(limit.Requests, 10))
+	c.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	c.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.ResetAfter), 10))
+	if !result.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+		return problems.RateLimited("Too many requests; retry after the time in the Retry-After header")
+	}
+	return nil
+}
+
+//Identifica o cliente para o limitador: a chave de API, o subject do token JWT ou o IP.
+func rateLimitClient(c *fiber.Ctx) string {
+	if key, ok := CurrentAPIKey(c); ok {
+		return "apikey:" + key.Id.Hex()
+	}
+	if subject := Subject(c); subject != "" {
+		return "jwt:" + subject
+	}
+	return "ip:" + c.IP()
+}
+
+//Arredonda a duração para cima em segundos inteiros, como pedem os cabeçalhos.
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+)
+
+//Store que sempre falha, para conferir que o limitador não derruba a API.
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit stores.RateLimit) (stores.RateLimitResult, error) {
+	return stores.RateLimitResult{}, errors.New("store down")
+}
+
+//Servidor com LimitByIP e RateLimiter na mesma ordem das rotas. A autenticação aceita só o token "valid"
+//e conta quantas vezes foi executada.
+func newRateLimitTestApp(store stores.RateLimitStore, ip, client stores.RateLimit) (*fiber.App, *int) {
+	authentications := 0
+	authenticate := func(c *fiber.Ctx) error {
+		authentications++
+		if c.Get(fiber.HeaderAuthorization) != "Bearer valid" {
+			return problems.Unauthorized("invalid token")
+		}
+		c.Locals(SubjectLocalsKey, "alice")
+		return c.Next()
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Get("/", LimitByIP(store, ip, time.Second, authenticate), RateLimiter(store, client, client, time.Second), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+	return app, &authentications
+}
+
+func rateLimitRequest(t *testing.T, app *fiber.App, token string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestLimitByIPThrottlesInvalidCredentials(t *testing.T) {
+	app, authentications := newRateLimitTestApp(stores.NewMemoryRateLimitStore(),
+		stores.RateLimit{Requests: 3, Window: time.Hour}, stores.RateLimit{Requests: 10, Window: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		if resp := rateLimitRequest(t, app, "invalid"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("request %d: status = %d, want 401", i+1, resp.StatusCode)
+		}
+	}
+	resp := rateLimitRequest(t, app, "invalid")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get(fiber.HeaderRetryAfter) == "" || resp.Header.Get("RateLimit-Limit") != "3" {
+		t.Errorf("headers = %v", resp.Header)
+	}
+	//A autenticação não é executada depois que o orçamento do IP acaba.
+	if *authentications != 3 {
+		t.Errorf("authenticate ran %d times, want 3", *authentications)
+	}
+	//O orçamento é do IP, então também vale para credenciais válidas.
+	if resp := rateLimitRequest(t, app, "valid"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("valid token: status = %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterLimitsAuthenticatedClients(t *testing.T) {
+	app, _ := newRateLimitTestApp(stores.NewMemoryRateLimitStore(),
+		stores.RateLimit{Requests: 10, Window: time.Hour}, stores.RateLimit{Requests: 2, Window: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		resp := rateLimitRequest(t, app, "valid")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want 204", i+1, resp.StatusCode)
+		}
+		//Os cabeçalhos são os do orçamento do cliente, consumido depois do orçamento do IP.
+		if resp.Header.Get("RateLimit-Limit") != "2" {
+			t.Errorf("RateLimit-Limit = %q, want 2", resp.Header.Get("RateLimit-Limit"))
+		}
+	}
+	if resp := rateLimitRequest(t, app, "valid"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterAllowsWhenStoreFails(t *testing.T) {
+	limit := stores.RateLimit{Requests: 1, Window: time.Hour}
+	app, _ := newRateLimitTestApp(failingRateLimitStore{}, limit, limit)
+	for i := 0; i < 3; i++ {
+		if resp := rateLimitRequest(t, app, "valid"); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("request %d: status = %d, want 204", i+1, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/nathanfernande/golang-mongodb-api/logging"
+)
+
+//RequestIDHeader é o cabeçalho com o ID da requisição, lido da requisição e devolvido na resposta.
+const RequestIDHeader = "X-Request-ID"
+
+//Tamanho máximo de um X-Request-ID recebido; IDs maiores são substituídos por um novo.
+const maxRequestIDLength = 128
+
+//RequestID retorna um middleware que dá um ID a cada requisição: o do cabeçalho X-Request-ID, se for válido,
+//ou um novo ID aleatório. O ID é devolvido no mesmo cabeçalho da resposta e guardado em c.UserContext()
+//(ver logging.WithRequestID), para aparecer em todas as linhas de log da requisição.
+//Deve ser o primeiro middleware registrado com app.Use.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		} else {
+			requestID = utils.CopyString(requestID)
+		}
+		c.Set(RequestIDHeader, requestID)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), requestID))
+		return c.Next()
+	}
+}
+
+//Aceita apenas IDs curtos com letras, números e - _ . :, para que o valor recebido não altere as linhas de log.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+//Gera um ID aleatório de 128 bits em hexadecimal.
+func newRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+//AccessLog retorna um middleware que escreve uma linha de log por requisição, com o método, o padrão da rota,
+//o caminho, o status, a latência e o tamanho do corpo da resposta. Deve vir depois de RequestID e de Tracing,
+//para que a linha tenha o request_id e o trace_id. Respostas 5xx são registradas com nível ERROR.
+//As rotas em quietRoutes (ex.: as sondas /healthz e /readyz) são registradas com nível DEBUG.
+//Os erros retornados pelas rotas são convertidos em resposta aqui, pelo ErrorHandler da aplicação, para que o status
+//e o tamanho registrados (e os vistos por Tracing) sejam os da resposta enviada.
+func AccessLog(quietRoutes ...string) fiber.Handler {
+	quiet := map[string]bool{}
+	for _, route := range quietRoutes {
+		quiet[route] = true
+	}
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		use := c.Route()
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		route := routePattern(c, use)
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if quiet[route] {
+			level = slog.LevelDebug
+		}
+		//Em GET /users/stream e GET /users/export.csv, o corpo é escrito depois e o tamanho não é conhecido (-1).
+		bytes := len(c.Response().Body())
+		if c.Response().IsBodyStream() {
+			bytes = -1
+		}
+		slog.LogAttrs(c.UserContext(), level, "request",
+			slog.String("method", c.Method()),
+			slog.String("route", route),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", bytes),
+			slog.String("ip", c.IP()),
+		)
+		return nil
+	}
+}
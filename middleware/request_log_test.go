@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/logging"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+)
+
+//Troca o logger padrão por um que escreve JSON em um buffer, a partir do nível DEBUG, e o restaura no fim do teste.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buffer bytes.Buffer
+	logger, err := logging.New(&buffer, logging.FormatJSON, slog.LevelDebug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buffer
+}
+
+//Decodifica as linhas de log JSON do buffer e o esvazia.
+func logLines(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %q", line)
+		}
+		lines = append(lines, entry)
+	}
+	buffer.Reset()
+	return lines
+}
+
+//Servidor com RequestID e AccessLog, como em app.go. GET /user/:userId escreve uma linha de log no handler.
+func newRequestLogTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(true)})
+	app.Use(RequestID(), AccessLog("/healthz"))
+	app.Get("/user/:userId", func(c *fiber.Ctx) error {
+		slog.InfoContext(c.UserContext(), "loading user", "userId", c.Params("userId"))
+		return c.SendString("ok")
+	})
+	app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendString("alive") })
+	app.Get("/fail", func(c *fiber.Ctx) error { return problems.Internal(errors.New("connection reset")) })
+	return app
+}
+
+func TestRequestID(t *testing.T) {
+	buffer := captureLogs(t)
+	app := newRequestLogTestApp()
+
+	tests := []struct {
+		name  string
+		sent  string
+		reuse bool
+	}{
+		{"valid id", "req-42_a.b:c", true},
+		{"missing", "", false},
+		{"invalid characters", "abc def", false},
+		{"log injection", `abc" level=ERROR`, false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"max length", strings.Repeat("a", maxRequestIDLength), true},
+	}
+	seen := map[string]bool{}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/user/42", nil)
+		if test.sent != "" {
+			req.Header.Set(RequestIDHeader, test.sent)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		requestID := resp.Header.Get(RequestIDHeader)
+		if test.reuse && requestID != test.sent {
+			t.Errorf("%s: X-Request-ID = %q, want the one sent", test.name, requestID)
+		}
+		if !test.reuse && (len(requestID) != 32 || strings.Trim(requestID, "0123456789abcdef") != "" || seen[requestID]) {
+			t.Errorf("%s: X-Request-ID = %q, want a new 32-character hex ID", test.name, requestID)
+		}
+		seen[requestID] = true
+
+		//A linha do handler e a do log de acesso têm o mesmo request_id da resposta.
+		lines := logLines(t, buffer)
+		if len(lines) != 2 || lines[0]["msg"] != "loading user" || lines[1]["msg"] != "request" {
+			t.Fatalf("%s: log lines = %v, want the handler line and the access log", test.name, lines)
+		}
+		for _, line := range lines {
+			if line["request_id"] != requestID {
+				t.Errorf("%s: %q line request_id = %v, want %q", test.name, line["msg"], line["request_id"], requestID)
+			}
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	buffer := captureLogs(t)
+	app := newRequestLogTestApp()
+
+	tests := []struct {
+		path   string
+		level  string
+		route  string
+		status float64
+	}{
+		{"/user/42", "INFO", "/user/:userId", http.StatusOK},
+		{"/healthz", "DEBUG", "/healthz", http.StatusOK},
+		{"/missing", "INFO", unmatchedRoute, http.StatusNotFound},
+		//O erro é convertido em resposta pelo ErrorHandler antes de a linha ser escrita.
+		{"/fail", "ERROR", "/fail", http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", test.path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != int(test.status) {
+			t.Errorf("GET %s: status = %d, want %v", test.path, resp.StatusCode, test.status)
+		}
+
+		var access map[string]interface{}
+		for _, line := range logLines(t, buffer) {
+			if line["msg"] == "request" {
+				access = line
+			}
+		}
+		if access == nil {
+			t.Errorf("GET %s: no access log line", test.path)
+			continue
+		}
+		if access["level"] != test.level || access["route"] != test.route || access["path"] != test.path || access["status"] != test.status || access["method"] != "GET" {
+			t.Errorf("GET %s: access log = %v, want level %s, route %s and status %v", test.path, access, test.level, test.route, test.status)
+		}
+		if _, ok := access["latency_ms"].(float64); !ok || access["request_id"] == nil || access["ip"] != "0.0.0.0" {
+			t.Errorf("GET %s: access log = %v, want latency_ms, request_id and ip", test.path, access)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/nathanfernande/golang-mongodb-api/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Tracing retorna um middleware que cria um span de servidor para cada requisição, com os spans do store como filhos.
+//Se a requisição tiver o cabeçalho traceparent (W3C Trace Context), o span continua o trace de quem chamou.
+//O contexto com o span é guardado em c.UserContext(), de onde os handlers e os middlewares derivam os contextos
+//das operações no banco. Deve ser registrado com app.Use antes das rotas.
+func Tracing(provider trace.TracerProvider) fiber.Handler {
+	tracer := provider.Tracer(tracing.ScopeName)
+	propagator := propagation.TraceContext{}
+	return func(c *fiber.Ctx) error {
+		ctx := propagator.Extract(c.UserContext(), headerCarrier{c})
+		method := utils.CopyString(c.Method())
+		ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLPath(utils.CopyString(c.Path())),
+			semconv.ClientAddress(c.IP()),
+		))
+		defer span.End()
+
+		use := c.Route()
+		c.SetUserContext(ctx)
+		err := c.Next()
+
+		//O nome do span usa o padrão da rota (ex.: "PUT /user/:userId"), conhecido só depois do roteamento.
+		status := responseStatus(c, err)
+		if route := routePattern(c, use); route != unmatchedRoute {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		//Pela convenção do OpenTelemetry, só respostas 5xx marcam o span de servidor como erro.
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+			if err != nil {
+				span.RecordError(err)
+			}
+		}
+		return err
+	}
+}
+
+//Adapta os cabeçalhos da requisição do Fiber à interface propagation.TextMapCarrier.
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+//O valor é copiado porque o propagador guarda partes dele (ex.: tracestate) além da duração da requisição.
+func (h headerCarrier) Get(key string) string {
+	return utils.CopyString(h.c.Get(key))
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := []string{}
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/controllers"
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"github.com/nathanfernande/golang-mongodb-api/problems"
+	"github.com/nathanfernande/golang-mongodb-api/stores"
+	"github.com/nathanfernande/golang-mongodb-api/tracing"
+	"github.com/nathanfernande/golang-mongodb-api/tracing/tracingtest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//UserStore em memória que, em Get, envia ao monitor os mesmos eventos que o driver do MongoDB enviaria para um find.
+type monitoredUserStore struct {
+	stores.UserStore
+	monitor   *event.CommandMonitor
+	requestID atomic.Int64
+}
+
+func (s *monitoredUserStore) Get(ctx context.Context, id primitive.ObjectID, includeDeleted bool) (models.User, error) {
+	command, err := bson.Marshal(bson.D{{Key: "find", Value: "users"}})
+	if err != nil {
+		return models.User{}, err
+	}
+	requestID := s.requestID.Add(1)
+	const connectionID = "localhost:27017[-1]"
+	s.monitor.Started(ctx, &event.CommandStartedEvent{Command: command, DatabaseName: "test", CommandName: "find", RequestID: requestID, ConnectionID: connectionID})
+	user, err := s.UserStore.Get(ctx, id, includeDeleted)
+	s.monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", DatabaseName: "test", RequestID: requestID, ConnectionID: connectionID}})
+	return user, err
+}
+
+func spanAttribute(attributes []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, kv := range attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingRequestSpans(t *testing.T) {
+	provider, exporter := tracingtest.NewInMemoryProvider()
+	store := &monitoredUserStore{UserStore: stores.NewMemoryUserStore(), monitor: tracing.CommandMonitor(provider)}
+	user, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: "Ana", Location: "Lisbon", Title: "Engineer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := controllers.NewUserController(store, stores.NewMemoryAuditStore(), time.Hour, controllers.Timeouts{Request: time.Second, Bulk: time.Second, Import: time.Second})
+
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Use(Tracing(provider))
+	app.Get("/user/:userId", uc.GetAUser)
+
+	tests := []struct {
+		name        string
+		target      string
+		traceparent string
+		status      int
+	}{
+		{"with traceparent", "/user/" + user.Id.Hex(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", http.StatusOK},
+		{"without traceparent", "/user/" + user.Id.Hex(), "", http.StatusOK},
+		{"not found", "/user/" + primitive.NewObjectID().Hex(), "", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exporter.Reset()
+			req := httptest.NewRequest("GET", test.target, nil)
+			if test.traceparent != "" {
+				req.Header.Set("traceparent", test.traceparent)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != test.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, test.status)
+			}
+
+			//O span do comando termina antes do span da requisição.
+			spans := exporter.GetSpans()
+			if len(spans) != 2 {
+				t.Fatalf("got %d spans, want 2", len(spans))
+			}
+			command, server := spans[0], spans[1]
+
+			if server.Name != "GET /user/:userId" || server.SpanKind != trace.SpanKindServer {
+				t.Errorf("server span = %q (%v)", server.Name, server.SpanKind)
+			}
+			if route := spanAttribute(server.Attributes, semconv.HTTPRouteKey).AsString(); route != "/user/:userId" {
+				t.Errorf("http.route = %q", route)
+			}
+			if status := spanAttribute(server.Attributes, semconv.HTTPResponseStatusCodeKey).AsInt64(); status != int64(test.status) {
+				t.Errorf("http.response.status_code = %d, want %d", status, test.status)
+			}
+			//Respostas 4xx não marcam o span de servidor como erro.
+			if server.Status.Code != codes.Unset {
+				t.Errorf("server span status = %v", server.Status)
+			}
+
+			//O traceparent é continuado: mesmo trace e o span de quem chamou como pai.
+			if test.traceparent != "" {
+				if traceID := server.SpanContext.TraceID().String(); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+					t.Errorf("trace ID = %s, want the one from traceparent", traceID)
+				}
+				if !server.Parent.IsRemote() || server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+					t.Errorf("server span parent = %v, want the span from traceparent", server.Parent)
+				}
+			} else if server.Parent.IsValid() {
+				t.Errorf("server span parent = %v, want a new trace", server.Parent)
+			}
+
+			if command.Name != "find users" || command.SpanKind != trace.SpanKindClient {
+				t.Errorf("command span = %q (%v)", command.Name, command.SpanKind)
+			}
+			if command.Parent.SpanID() != server.SpanContext.SpanID() || command.SpanContext.TraceID() != server.SpanContext.TraceID() {
+				t.Errorf("command span parent = %v, want the server span %v", command.Parent, server.SpanContext)
+			}
+			if collection := spanAttribute(command.Attributes, semconv.DBCollectionNameKey).AsString(); collection != "users" {
+				t.Errorf("db.collection.name = %q", collection)
+			}
+		})
+	}
+}
+
+func TestTracingMarksServerErrors(t *testing.T) {
+	provider, exporter := tracingtest.NewInMemoryProvider()
+	app := fiber.New(fiber.Config{ErrorHandler: problems.ErrorHandler(false)})
+	app.Use(Tracing(provider))
+	app.Get("/fail", func(c *fiber.Ctx) error { return problems.Internal(context.DeadlineExceeded) })
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/fail", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error || len(spans[0].Events) == 0 {
+		t.Errorf("span status = %v with %d events, want an error with the recorded exception", spans[0].Status, len(spans[0].Events))
+	}
+}
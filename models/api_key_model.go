@@ -0,0 +1,41 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Scopes que podem ser concedidos a uma chave de API. Cada rota exige um scope (ver routes.UserRoute).
+const (
+    ScopeUsersRead    = "users:read"    //Consultar usuários (GET)
+    ScopeUsersWrite   = "users:write"   //Criar, editar, restaurar e importar usuários
+    ScopeUsersDelete  = "users:delete"  //Excluir usuários e remover definitivamente os excluídos
+    ScopeAPIKeysAdmin = "apikeys:admin" //Criar, listar e revogar chaves de API
+)
+
+//Define uma struct chamada APIKey que representa uma chave de API usada por serviços (ex.: jobs em lote) que não fazem login interativo.
+//A chave em si só é mostrada uma vez, na criação; no banco fica apenas o hash SHA-256 dela.
+
+type APIKey struct {
+    Id         primitive.ObjectID `json:"id" bson:"id"`
+    Name       string             `json:"name" bson:"name"`             //Nome descritivo (ex.: "nightly-sync")
+    Prefix     string             `json:"prefix" bson:"prefix"`         //Início da chave, para identificá-la sem revelar o segredo
+    Hash       string             `json:"-" bson:"hash"`                //Hash SHA-256 (hex) da chave completa; nunca é retornado na API
+    Scopes     []string           `json:"scopes" bson:"scopes"`         //Constantes Scope*
+    CreatedBy  string             `json:"createdBy" bson:"createdBy"`   //Quem criou a chave
+    CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+    ExpiresAt  *time.Time         `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`   //nil para chaves sem validade
+    LastUsedAt *time.Time         `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"` //Último uso, atualizado no máximo uma vez por minuto
+    RevokedAt  *time.Time         `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`   //Preenchido quando a chave é revogada
+}
+
+//HasScope informa se a chave concede o scope.
+func (k APIKey) HasScope(scope string) bool {
+    for _, granted := range k.Scopes {
+        if granted == scope {
+            return true
+        }
+    }
+    return false
+}
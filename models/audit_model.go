@@ -0,0 +1,29 @@
+package models
+
+import (
+    "time"
+
+    "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Operações registradas na trilha de auditoria de usuários.
+const (
+    AuditCreate  = "create"
+    AuditUpdate  = "update"
+    AuditDelete  = "delete"
+    AuditRestore = "restore"
+    AuditPurge   = "purge"
+)
+
+//Define uma struct chamada AuditEntry que representa um registro da trilha de auditoria: quem alterou qual usuário, quando e como.
+//Os registros ficam em uma coleção separada da coleção de usuários e nunca são alterados depois de gravados.
+
+type AuditEntry struct {
+    Id        primitive.ObjectID `json:"id" bson:"id"`
+    UserId    primitive.ObjectID `json:"userId" bson:"userId"`       //ID do usuário alterado
+    Operation string             `json:"operation" bson:"operation"` //Uma das constantes Audit* (create, update, delete, restore, purge)
+    Actor     string             `json:"actor" bson:"actor"`         //Quem fez a alteração
+    Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+    Before    *User              `json:"before,omitempty" bson:"before,omitempty"` //Estado do usuário antes da alteração; nil na criação
+    After     *User              `json:"after,omitempty" bson:"after,omitempty"`   //Estado do usuário depois da alteração; nil na remoção definitiva
+}
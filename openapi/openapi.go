@@ -0,0 +1,60 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//Especificação OpenAPI 3.1 da API, escrita em YAML para facilitar a revisão.
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+//Página da documentação interativa (Swagger UI), que lê a especificação de GET /openapi.json.
+//
+//go:embed docs.html
+var docsHTML []byte
+
+//Arquivos do Swagger UI usados pela página, copiados do swagger-ui-dist (versão e licença em swagger-ui/NOTICE).
+//Ficam no binário para que a página não carregue scripts de uma CDN.
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css
+var docsAssets embed.FS
+
+//A conversão para JSON é feita uma única vez, na primeira chamada de Spec.
+var specJSON = sync.OnceValues(func() ([]byte, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(specYAML, &document); err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI spec: %w", err)
+	}
+	spec, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("converting OpenAPI spec to JSON: %w", err)
+	}
+	return spec, nil
+})
+
+//Spec retorna a especificação OpenAPI da API em JSON, servida em GET /openapi.json (ver routes.DocsRoute).
+func Spec() ([]byte, error) {
+	return specJSON()
+}
+
+//DocsPage retorna a página HTML da documentação interativa, servida em GET /docs.
+func DocsPage() []byte {
+	return docsHTML
+}
+
+//DocsAssets retorna os arquivos do Swagger UI que a página carrega, servidos em GET /docs/assets/ (ver routes.DocsRoute).
+func DocsAssets() fs.FS {
+	assets, err := fs.Sub(docsAssets, "swagger-ui")
+	if err != nil {
+		//Só acontece se o diretório embutido mudar de nome.
+		panic(err)
+	}
+	return assets
+}
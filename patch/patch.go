@@ -0,0 +1,66 @@
+//Package patch aplica os patches aceitos por PATCH /user/:userId. Fica fora de controllers para que o middleware de políticas
+//avalie users:update sobre o mesmo documento que o controller vai gravar, sem depender do pacote controllers.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5" //Aplica JSON Merge Patch (RFC 7396) e JSON Patch (RFC 6902).
+	"github.com/nathanfernande/golang-mongodb-api/models"
+)
+
+//Tipos de conteúdo aceitos por PATCH /user/:userId.
+const (
+	//JSON Merge Patch (RFC 7396): um documento parcial; campos presentes substituem os atuais e null remove o campo.
+	MergePatchContentType = "application/merge-patch+json"
+	//JSON Patch (RFC 6902): uma lista de operações (add, remove, replace, move, copy, test).
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+//ErrUnsupportedMediaType é retornado por ApplyUser quando o Content-Type não é um dos dois tipos de patch aceitos.
+var ErrUnsupportedMediaType = errors.New("Content-Type must be " + MergePatchContentType + " or " + JSONPatchContentType)
+
+//MediaType retorna o tipo de patch do cabeçalho Content-Type (sem parâmetros e em minúsculas), ou "" se não for um dos dois aceitos.
+func MediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType != MergePatchContentType && mediaType != JSONPatchContentType {
+		return ""
+	}
+	return mediaType
+}
+
+//ApplyUser aplica o patch ao usuário, como JSON Merge Patch ou JSON Patch conforme o Content-Type, e retorna o documento resultante.
+//É usado por PatchAUser e pelo middleware de políticas, que avalia as condições de users:update também sobre o usuário já alterado.
+func ApplyUser(contentType string, user models.User, patch []byte) (models.User, error) {
+	original, err := json.Marshal(user)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	//Aplica o patch sobre a representação JSON do usuário.
+	var patched []byte
+	switch MediaType(contentType) {
+	case MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatchContentType:
+		var operations jsonpatch.Patch
+		if operations, err = jsonpatch.DecodePatch(patch); err == nil {
+			patched, err = operations.Apply(original)
+		}
+	default:
+		err = ErrUnsupportedMediaType
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	//Converte o documento resultante de volta para models.User.
+	var patchedUser models.User
+	if err := json.Unmarshal(patched, &patchedUser); err != nil {
+		return models.User{}, err
+	}
+	return patchedUser, nil
+}
@@ -0,0 +1,60 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMediaType(t *testing.T) {
+	tests := map[string]string{
+		"application/merge-patch+json":                MergePatchContentType,
+		"Application/Merge-Patch+JSON; charset=utf-8": MergePatchContentType,
+		" application/json-patch+json ":               JSONPatchContentType,
+		"application/json":                            "",
+		"application/merge-patch+json-seq":            "",
+		"":                                            "",
+	}
+	for contentType, want := range tests {
+		if got := MediaType(contentType); got != want {
+			t.Errorf("MediaType(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
+
+func TestApplyUser(t *testing.T) {
+	user := models.User{Id: primitive.NewObjectID(), Name: "Ana", Location: "Lisbon", Title: "Engineer", Version: 1}
+
+	tests := []struct {
+		contentType string
+		patch       string
+		want        models.User
+	}{
+		{MergePatchContentType, `{"location":"Porto"}`, models.User{Id: user.Id, Name: "Ana", Location: "Porto", Title: "Engineer", Version: 1}},
+		{MergePatchContentType, `{"title":null}`, models.User{Id: user.Id, Name: "Ana", Location: "Lisbon", Version: 1}},
+		{JSONPatchContentType, `[{"op":"test","path":"/name","value":"Ana"},{"op":"replace","path":"/title","value":"Manager"}]`, models.User{Id: user.Id, Name: "Ana", Location: "Lisbon", Title: "Manager", Version: 1}},
+	}
+	for _, test := range tests {
+		got, err := ApplyUser(test.contentType, user, []byte(test.patch))
+		if err != nil {
+			t.Errorf("ApplyUser(%s, %s) error = %v", test.contentType, test.patch, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ApplyUser(%s, %s) = %+v, want %+v", test.contentType, test.patch, got, test.want)
+		}
+	}
+
+	//O usuário recebido não é alterado.
+	if user.Location != "Lisbon" || user.Title != "Engineer" {
+		t.Errorf("ApplyUser changed its argument: %+v", user)
+	}
+	if _, err := ApplyUser("application/json", user, []byte(`{"title":"Manager"}`)); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("ApplyUser with application/json error = %v, want ErrUnsupportedMediaType", err)
+	}
+	if _, err := ApplyUser(JSONPatchContentType, user, []byte(`[{"op":"test","path":"/name","value":"Bruno"}]`)); err == nil {
+		t.Error("ApplyUser with a failing test operation succeeded")
+	}
+}
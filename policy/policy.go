@@ -0,0 +1,314 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"gopkg.in/yaml.v3"
+)
+
+//Ações avaliadas pelo Engine. Cada rota protegida declara a sua (ver routes.UserRoute).
+const (
+	ActionUsersCreate  = "users:create"
+	ActionUsersRead    = "users:read" //Consultar um usuário e o seu histórico
+	ActionUsersList    = "users:list" //Listar, buscar, exportar e fazer streaming de usuários
+	ActionUsersUpdate  = "users:update"
+	ActionUsersDelete  = "users:delete"
+	ActionUsersRestore = "users:restore"
+	ActionUsersPurge   = "users:purge"
+	ActionUsersBulk    = "users:bulk"
+	ActionUsersImport  = "users:import"
+	ActionAPIKeysAdmin = "apikeys:admin"
+)
+
+//Nome informado na decisão quando nenhum papel ou regra permite a ação.
+const DefaultDenyRule = "default-deny"
+
+//Qualquer papel ou ação, em roles, permissions e actions.
+const wildcard = "*"
+
+//Campos de models.User que podem ser usados nas condições das regras.
+var userAttributes = map[string]func(models.User) string{
+	"name":     func(user models.User) string { return user.Name },
+	"location": func(user models.User) string { return user.Location },
+	"title":    func(user models.User) string { return user.Title },
+}
+
+//Policy é o conteúdo do arquivo YAML de políticas.
+//
+//	roles:
+//	  admin:
+//	    permissions: ["*"]
+//	  viewer:
+//	    permissions: [users:read, users:list]
+//	rules:
+//	  - name: managers-edit-own-location
+//	    effect: allow
+//	    roles: [manager]
+//	    actions: [users:update]
+//	    conditions:
+//	      - {field: location, op: eq, subject: location}
+//	  - name: only-admins-delete
+//	    effect: deny
+//	    roles: [manager, viewer]
+//	    actions: [users:delete, users:purge]
+type Policy struct {
+	//Roles concede permissões incondicionais a cada papel.
+	Roles map[string]Role `yaml:"roles"`
+	//Rules são regras nomeadas que permitem ou negam ações, opcionalmente com condições sobre o usuário alvo.
+	Rules []Rule `yaml:"rules"`
+}
+
+//Role é um papel, recebido na claim "roles" do token JWT.
+type Role struct {
+	//Permissions são as ações que o papel pode executar sobre qualquer usuário. "*" permite todas.
+	Permissions []string `yaml:"permissions"`
+}
+
+//Rule permite (effect: allow) ou nega (effect: deny) as ações para os papéis quando todas as condições são satisfeitas.
+//Regras com condições só se aplicam a ações sobre um usuário específico (ex.: users:update); nas listagens e
+//operações em lote, em que não há um usuário alvo, elas nunca são satisfeitas.
+//Uma condição que depende de uma claim ausente no token não pode ser avaliada: ela satisfaz as regras deny
+//e nunca as regras allow, para que a falta de um atributo negue o acesso em vez de liberá-lo.
+type Rule struct {
+	Name       string      `yaml:"name"`
+	Effect     string      `yaml:"effect"`
+	Roles      []string    `yaml:"roles"`
+	Actions    []string    `yaml:"actions"`
+	Conditions []Condition `yaml:"conditions"`
+}
+
+//Condition compara um campo do usuário alvo (name, location ou title) com valores fixos ou com um atributo de quem faz a requisição.
+//
+//	{field: location, op: eq, subject: location}   //o usuário alvo está na mesma location do token
+//	{field: title, op: in, values: [Engineer, Intern]}
+//	{field: title, op: ne, value: Director}
+type Condition struct {
+	Field string `yaml:"field"`
+	//Op é eq, ne ou in.
+	Op     string   `yaml:"op"`
+	Value  string   `yaml:"value"`
+	Values []string `yaml:"values"`
+	//Subject é o nome de uma claim do token; o valor dela é usado no lugar de value.
+	Subject string `yaml:"subject"`
+}
+
+//Subject é quem faz a requisição: os papéis e os atributos (claims) do token.
+type Subject struct {
+	Id         string
+	Roles      []string
+	Attributes map[string]string
+}
+
+//Decision é o resultado da avaliação. Rule é o nome da regra que decidiu (DefaultDenyRule quando nada permite a ação,
+//ou "role:<papel>" quando a permissão veio de um papel).
+type Decision struct {
+	Allowed bool
+	Rule    string
+}
+
+//Engine avalia as políticas carregadas. É imutável e pode ser usado por várias requisições ao mesmo tempo.
+type Engine struct {
+	policy Policy
+}
+
+//Load lê e valida o arquivo YAML de políticas.
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+//Parse valida as políticas em YAML e cria o Engine. Campos, efeitos, operadores ou ações desconhecidos são erros,
+//para que um erro de digitação no arquivo não conceda nem retire acesso silenciosamente.
+func Parse(data []byte) (*Engine, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var policy Policy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	return &Engine{policy: policy}, nil
+}
+
+func (p Policy) validate() error {
+	for name, role := range p.Roles {
+		for _, permission := range role.Permissions {
+			if !isAction(permission) {
+				return fmt.Errorf("role %q: unknown permission %q", name, permission)
+			}
+		}
+	}
+	names := map[string]bool{}
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d: missing name", i)
+		}
+		if names[rule.Name] || rule.Name == DefaultDenyRule {
+			return fmt.Errorf("rule %q: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.Effect != "allow" && rule.Effect != "deny" {
+			return fmt.Errorf("rule %q: effect must be allow or deny", rule.Name)
+		}
+		if len(rule.Roles) == 0 || len(rule.Actions) == 0 {
+			return fmt.Errorf("rule %q: roles and actions are required", rule.Name)
+		}
+		for _, action := range rule.Actions {
+			if !isAction(action) {
+				return fmt.Errorf("rule %q: unknown action %q", rule.Name, action)
+			}
+		}
+		for _, condition := range rule.Conditions {
+			if err := condition.validate(); err != nil {
+				return fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Condition) validate() error {
+	if _, ok := userAttributes[c.Field]; !ok {
+		return fmt.Errorf("unknown field %q", c.Field)
+	}
+	switch c.Op {
+	case "eq", "ne":
+		if len(c.Values) > 0 || (c.Subject == "") == (c.Value == "") {
+			return fmt.Errorf("%s condition on %q needs exactly one of value or subject", c.Op, c.Field)
+		}
+	case "in":
+		if len(c.Values) == 0 || c.Value != "" || c.Subject != "" {
+			return fmt.Errorf("in condition on %q needs values", c.Field)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Op)
+	}
+	return nil
+}
+
+func isAction(action string) bool {
+	switch action {
+	case wildcard, ActionUsersCreate, ActionUsersRead, ActionUsersList, ActionUsersUpdate, ActionUsersDelete,
+		ActionUsersRestore, ActionUsersPurge, ActionUsersBulk, ActionUsersImport, ActionAPIKeysAdmin:
+		return true
+	}
+	return false
+}
+
+//Evaluate decide se o subject pode executar a ação sobre o usuário alvo (nil quando a ação não tem um alvo).
+//
+//A ordem é: (1) qualquer regra deny satisfeita nega, (2) uma permissão de papel permite,
+//(3) uma regra allow satisfeita permite e (4) se nada permitir, a ação é negada por DefaultDenyRule.
+func (e *Engine) Evaluate(subject Subject, action string, resource *models.User) Decision {
+	for _, rule := range e.policy.Rules {
+		if rule.Effect == "deny" && rule.matches(subject, action, resource) {
+			return Decision{Allowed: false, Rule: rule.Name}
+		}
+	}
+	for _, role := range subject.Roles {
+		if contains(e.policy.Roles[role].Permissions, action) {
+			return Decision{Allowed: true, Rule: "role:" + role}
+		}
+	}
+	for _, rule := range e.policy.Rules {
+		if rule.Effect == "allow" && rule.matches(subject, action, resource) {
+			return Decision{Allowed: true, Rule: rule.Name}
+		}
+	}
+	return Decision{Allowed: false, Rule: DefaultDenyRule}
+}
+
+//Informa se a regra se aplica: algum papel e a ação correspondem e todas as condições são satisfeitas.
+func (r Rule) matches(subject Subject, action string, resource *models.User) bool {
+	if !contains(r.Actions, action) {
+		return false
+	}
+	roleMatches := false
+	for _, role := range subject.Roles {
+		if contains(r.Roles, role) {
+			roleMatches = true
+			break
+		}
+	}
+	if !roleMatches {
+		return false
+	}
+	if len(r.Conditions) > 0 && resource == nil {
+		return false
+	}
+	for _, condition := range r.Conditions {
+		satisfied, ok := condition.matches(subject, *resource)
+		if !ok {
+			//Claim ausente: nega com as regras deny e não permite com as regras allow.
+			return r.Effect == "deny"
+		}
+		if !satisfied {
+			return false
+		}
+	}
+	return true
+}
+
+//Informa se a condição é satisfeita. ok é false quando a claim indicada em subject não está no token.
+func (c Condition) matches(subject Subject, resource models.User) (satisfied, ok bool) {
+	value := userAttributes[c.Field](resource)
+	expected := c.Value
+	if c.Subject != "" {
+		attribute, ok := subject.Attributes[c.Subject]
+		if !ok {
+			return false, false
+		}
+		expected = attribute
+	}
+	switch c.Op {
+	case "eq":
+		return value == expected, true
+	case "ne":
+		return value != expected, true
+	case "in":
+		return contains(c.Values, value), true
+	}
+	return false, true
+}
+
+//Informa se a lista contém o valor ou o curinga "*".
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value || item == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+//SubjectFromClaims monta o Subject a partir das claims de um token JWT.
+//Os papéis vêm da claim "roles" (lista ou string separada por espaços) e os atributos são todas as claims do tipo string.
+//Um token sem papéis só tem acesso ao que as regras concederem a "*".
+func SubjectFromClaims(subjectId string, claims map[string]interface{}) Subject {
+	subject := Subject{Id: subjectId, Attributes: map[string]string{}}
+	for name, value := range claims {
+		if text, ok := value.(string); ok {
+			subject.Attributes[name] = text
+		}
+	}
+	switch roles := claims["roles"].(type) {
+	case string:
+		subject.Roles = strings.Fields(roles)
+	case []interface{}:
+		for _, role := range roles {
+			if text, ok := role.(string); ok {
+				subject.Roles = append(subject.Roles, text)
+			}
+		}
+	}
+	return subject
+}
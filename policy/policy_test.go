@@ -0,0 +1,155 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+)
+
+//Carrega as políticas distribuídas em configs/policy.yaml.
+func loadTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	engine, err := Load("../configs/policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestEvaluate(t *testing.T) {
+	engine := loadTestEngine(t)
+	lisbon := &models.User{Name: "Ana", Location: "Lisbon", Title: "Engineer"}
+	porto := &models.User{Name: "André", Location: "Porto", Title: "Engineer"}
+	admin := Subject{Id: "admin", Roles: []string{"admin"}, Attributes: map[string]string{}}
+	manager := Subject{Id: "manager", Roles: []string{"manager"}, Attributes: map[string]string{"location": "Lisbon"}}
+	//Gerente cujo token não tem a claim location.
+	managerWithoutLocation := Subject{Id: "manager", Roles: []string{"manager"}, Attributes: map[string]string{}}
+	viewer := Subject{Id: "viewer", Roles: []string{"viewer"}, Attributes: map[string]string{"location": "Lisbon"}}
+	noRoles := Subject{Id: "nobody", Attributes: map[string]string{"location": "Lisbon"}}
+
+	tests := []struct {
+		name     string
+		subject  Subject
+		action   string
+		resource *models.User
+		want     Decision
+	}{
+		{"admin wildcard", admin, ActionUsersPurge, lisbon, Decision{true, "role:admin"}},
+		{"admin without resource", admin, ActionUsersBulk, nil, Decision{true, "role:admin"}},
+		{"viewer reads", viewer, ActionUsersRead, porto, Decision{true, "role:viewer"}},
+		{"viewer cannot update", viewer, ActionUsersUpdate, lisbon, Decision{false, DefaultDenyRule}},
+		{"viewer cannot delete", viewer, ActionUsersDelete, lisbon, Decision{false, "only-admins-delete"}},
+		{"manager cannot purge", manager, ActionUsersPurge, lisbon, Decision{false, "only-admins-delete"}},
+		{"manager updates own location", manager, ActionUsersUpdate, lisbon, Decision{true, "managers-edit-own-location"}},
+		{"manager restores own location", manager, ActionUsersRestore, lisbon, Decision{true, "managers-edit-own-location"}},
+		{"manager cannot update other location", manager, ActionUsersUpdate, porto, Decision{false, DefaultDenyRule}},
+		{"manager update without resource", manager, ActionUsersUpdate, nil, Decision{false, DefaultDenyRule}},
+		{"manager creates in own location", manager, ActionUsersCreate, lisbon, Decision{true, "role:manager"}},
+		{"manager cannot create in other location", manager, ActionUsersCreate, porto, Decision{false, "managers-create-own-location"}},
+		{"manager cannot bulk", manager, ActionUsersBulk, nil, Decision{false, DefaultDenyRule}},
+		{"no roles", noRoles, ActionUsersRead, lisbon, Decision{false, DefaultDenyRule}},
+		//Sem a claim location, a regra deny se aplica e a regra allow não.
+		{"missing claim denies create", managerWithoutLocation, ActionUsersCreate, porto, Decision{false, "managers-create-own-location"}},
+		{"missing claim denies create in any location", managerWithoutLocation, ActionUsersCreate, &models.User{}, Decision{false, "managers-create-own-location"}},
+		{"missing claim does not allow update", managerWithoutLocation, ActionUsersUpdate, lisbon, Decision{false, DefaultDenyRule}},
+		{"missing claim keeps role permissions", managerWithoutLocation, ActionUsersRead, porto, Decision{true, "role:manager"}},
+	}
+	for _, test := range tests {
+		if got := engine.Evaluate(test.subject, test.action, test.resource); got != test.want {
+			t.Errorf("%s: Evaluate = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEvaluateConditions(t *testing.T) {
+	engine, err := Parse([]byte(`
+rules:
+  - name: engineers
+    effect: allow
+    roles: ["*"]
+    actions: [users:read]
+    conditions:
+      - {field: title, op: in, values: [Engineer, Intern]}
+  - name: not-directors
+    effect: deny
+    roles: ["*"]
+    actions: [users:read]
+    conditions:
+      - {field: title, op: eq, value: Director}
+  - name: same-team
+    effect: allow
+    roles: [lead]
+    actions: [users:update]
+    conditions:
+      - {field: title, op: eq, value: Engineer}
+      - {field: location, op: eq, subject: team}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lead := Subject{Roles: []string{"lead"}, Attributes: map[string]string{"team": "Lisbon"}}
+
+	tests := []struct {
+		action   string
+		resource models.User
+		want     Decision
+	}{
+		{ActionUsersRead, models.User{Title: "Intern"}, Decision{true, "engineers"}},
+		{ActionUsersRead, models.User{Title: "Director"}, Decision{false, "not-directors"}},
+		{ActionUsersRead, models.User{Title: "Manager"}, Decision{false, DefaultDenyRule}},
+		{ActionUsersUpdate, models.User{Title: "Engineer", Location: "Lisbon"}, Decision{true, "same-team"}},
+		//Todas as condições precisam ser satisfeitas.
+		{ActionUsersUpdate, models.User{Title: "Engineer", Location: "Porto"}, Decision{false, DefaultDenyRule}},
+		{ActionUsersUpdate, models.User{Title: "Intern", Location: "Lisbon"}, Decision{false, DefaultDenyRule}},
+	}
+	for _, test := range tests {
+		resource := test.resource
+		if got := engine.Evaluate(lead, test.action, &resource); got != test.want {
+			t.Errorf("Evaluate(%s, %+v) = %+v, want %+v", test.action, test.resource, got, test.want)
+		}
+	}
+}
+
+func TestParseRejectsInvalidPolicies(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":        "roles:\n  admin:\n    permisions: [\"*\"]\n",
+		"unknown permission": "roles:\n  admin:\n    permissions: [users:write]\n",
+		"missing name":       "rules:\n  - {effect: allow, roles: [a], actions: [users:read]}\n",
+		"duplicate name":     "rules:\n  - {name: r, effect: allow, roles: [a], actions: [users:read]}\n  - {name: r, effect: deny, roles: [a], actions: [users:read]}\n",
+		"reserved name":      "rules:\n  - {name: default-deny, effect: allow, roles: [a], actions: [users:read]}\n",
+		"unknown effect":     "rules:\n  - {name: r, effect: permit, roles: [a], actions: [users:read]}\n",
+		"missing roles":      "rules:\n  - {name: r, effect: allow, actions: [users:read]}\n",
+		"unknown action":     "rules:\n  - {name: r, effect: allow, roles: [a], actions: [users:write]}\n",
+		"unknown field":      "rules:\n  - {name: r, effect: allow, roles: [a], actions: [users:read], conditions: [{field: password, op: eq, value: x}]}\n",
+		"unknown operator":   "rules:\n  - {name: r, effect: allow, roles: [a], actions: [users:read], conditions: [{field: name, op: regex, value: x}]}\n",
+		"value and subject":  "rules:\n  - {name: r, effect: allow, roles: [a], actions: [users:read], conditions: [{field: name, op: eq, value: x, subject: name}]}\n",
+		"in without values":  "rules:\n  - {name: r, effect: allow, roles: [a], actions: [users:read], conditions: [{field: name, op: in, value: x}]}\n",
+	}
+	for name, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil || !strings.HasPrefix(err.Error(), "invalid policy") {
+			t.Errorf("%s: Parse error = %v, want an invalid policy error", name, err)
+		}
+	}
+}
+
+func TestSubjectFromClaims(t *testing.T) {
+	subject := SubjectFromClaims("user-1", map[string]interface{}{
+		"roles":    []interface{}{"manager", 7, "viewer"},
+		"location": "Lisbon",
+		"exp":      1700000000.0,
+	})
+	if subject.Id != "user-1" || strings.Join(subject.Roles, ",") != "manager,viewer" {
+		t.Errorf("subject = %+v", subject)
+	}
+	if subject.Attributes["location"] != "Lisbon" {
+		t.Errorf("location attribute = %q", subject.Attributes["location"])
+	}
+	if _, ok := subject.Attributes["exp"]; ok {
+		t.Error("non-string claims must not become attributes")
+	}
+
+	if roles := SubjectFromClaims("user-1", map[string]interface{}{"roles": "admin  viewer"}).Roles; strings.Join(roles, ",") != "admin,viewer" {
+		t.Errorf("roles from a string claim = %v", roles)
+	}
+}
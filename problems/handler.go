@@ -0,0 +1,80 @@
+package problems
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nathanfernande/golang-mongodb-api/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//ContentType é o tipo de conteúdo das respostas de erro (RFC 7807).
+const ContentType = "application/problem+json"
+
+type fiberError = fiber.Error
+
+//Tipos dos erros do Fiber com um equivalente na API. Os demais usam TypeBlank.
+var fiberTypes = map[int]func(string) *Error{
+	http.StatusBadRequest:           BadRequest,
+	http.StatusUnauthorized:         Unauthorized,
+	http.StatusForbidden:            Forbidden,
+	http.StatusNotFound:             NotFound,
+	http.StatusConflict:             Conflict,
+	http.StatusPreconditionFailed:   PreconditionFailed,
+	http.StatusUnsupportedMediaType: UnsupportedMediaType,
+	http.StatusTooManyRequests:      RateLimited,
+	http.StatusServiceUnavailable:   Unavailable,
+}
+
+//Converte um erro do Fiber (ex.: 404 de uma rota inexistente ou 413 de um corpo grande demais) em um Error.
+func fiberProblem(err *fiber.Error) *Error {
+	if newProblem, ok := fiberTypes[err.Code]; ok {
+		return newProblem(err.Message)
+	}
+	return newError(err.Code, TypeBlank, http.StatusText(err.Code), err.Message)
+}
+
+//ErrorHandler retorna o fiber.ErrorHandler da aplicação, que responde os erros retornados pelos handlers e middlewares
+//com application/problem+json: type, title, status, detail, instance (o caminho da requisição), traceId, requestId
+//e os membros adicionais do Error. Erros que não são um Error nem um fiber.Error são respondidos como Internal.
+//
+//Cada erro é registrado no log com o contexto da requisição (WARN para 4xx e 503, ERROR para as demais falhas do servidor).
+//Com production, o detail das falhas internas não inclui a causa, que pode conter endereços e mensagens do banco de dados.
+func ErrorHandler(production bool) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		problem := fromError(err)
+		ctx := c.UserContext()
+
+		level := slog.LevelWarn
+		if problem.Status >= http.StatusInternalServerError && problem.Status != http.StatusServiceUnavailable {
+			level = slog.LevelError
+		}
+		slog.Log(ctx, level, "request failed",
+			"method", c.Method(), "path", c.Path(), "status", problem.Status, "type", problem.Type, "error", problem.Error())
+		if problem.Err != nil {
+			trace.SpanFromContext(ctx).RecordError(problem.Err)
+		}
+
+		detail := problem.Detail
+		if problem.Err != nil && !production {
+			detail = problem.Error()
+		}
+		body := fiber.Map{}
+		for key, value := range problem.Extensions {
+			body[key] = value
+		}
+		body["type"] = problem.Type
+		body["title"] = problem.Title
+		body["status"] = problem.Status
+		body["detail"] = detail
+		body["instance"] = c.Path()
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			body["traceId"] = spanContext.TraceID().String()
+		}
+		if requestID := logging.RequestID(ctx); requestID != "" {
+			body["requestId"] = requestID
+		}
+		return c.Status(problem.Status).JSON(body, ContentType)
+	}
+}
@@ -0,0 +1,66 @@
+package problems
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//Responde GET /fail com o erro informado, passando pelo ErrorHandler.
+func handleTestError(t *testing.T, production bool, err error) (*http.Response, map[string]any) {
+	t.Helper()
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(production)})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return err
+	})
+	resp, testErr := app.Test(httptest.NewRequest("GET", "/fail", nil), -1)
+	if testErr != nil {
+		t.Fatal(testErr)
+	}
+	defer resp.Body.Close()
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestErrorHandler(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.5:27017: connection refused")
+	tests := []struct {
+		name       string
+		production bool
+		err        error
+		wantStatus int
+		wantType   string
+		wantCause  bool
+	}{
+		{"internal in production", true, Internal(cause), http.StatusInternalServerError, TypeInternal, false},
+		{"internal in development", false, Internal(cause), http.StatusInternalServerError, TypeInternal, true},
+		{"plain error in production", true, cause, http.StatusInternalServerError, TypeInternal, false},
+		{"plain error in development", false, cause, http.StatusInternalServerError, TypeInternal, true},
+		{"not found", true, NotFound("User with specified ID not found!"), http.StatusNotFound, TypeNotFound, false},
+		{"fiber error", true, fiber.ErrMethodNotAllowed, http.StatusMethodNotAllowed, TypeBlank, false},
+	}
+	for _, tt := range tests {
+		resp, body := handleTestError(t, tt.production, tt.err)
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.wantStatus)
+		}
+		if got := resp.Header.Get(fiber.HeaderContentType); got != ContentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.name, got, ContentType)
+		}
+		if body["type"] != tt.wantType || body["status"] != float64(tt.wantStatus) || body["instance"] != "/fail" {
+			t.Errorf("%s: body = %v, want type %s, status %d and instance /fail", tt.name, body, tt.wantType, tt.wantStatus)
+		}
+		detail, _ := body["detail"].(string)
+		if hasCause := strings.Contains(detail, cause.Error()); hasCause != tt.wantCause {
+			t.Errorf("%s: detail = %q, want cause included = %v", tt.name, detail, tt.wantCause)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package problems
+
+import (
+	"errors"
+	"net/http"
+)
+
+//Tipos dos problemas (RFC 7807) retornados pela API. Os valores são estáveis: os clientes podem usá-los para decidir
+//como tratar um erro, sem depender do texto de title ou detail. São URIs relativas, documentadas em openapi/openapi.yaml.
+const (
+	TypeBadRequest           = "/problems/bad-request"
+	TypeValidation           = "/problems/validation"
+	TypeUnauthorized         = "/problems/unauthorized"
+	TypeForbidden            = "/problems/forbidden"
+	TypeNotFound             = "/problems/not-found"
+	TypeConflict             = "/problems/conflict"
+	TypePreconditionFailed   = "/problems/precondition-failed"
+	TypeUnsupportedMediaType = "/problems/unsupported-media-type"
+	TypeIdempotencyKeyReused = "/problems/idempotency-key-reused"
+	TypeRateLimited          = "/problems/rate-limited"
+	TypeInternal             = "/problems/internal"
+	TypeUnavailable          = "/problems/unavailable"
+	//TypeBlank é usado nos erros do próprio Fiber sem um tipo da API (ex.: 405 - Method Not Allowed); o title é o texto do status.
+	TypeBlank = "about:blank"
+)
+
+//Error é um erro da aplicação com o status HTTP e o tipo de problema com que deve ser respondido.
+//Handlers e middlewares retornam um Error e o ErrorHandler escreve a resposta application/problem+json.
+type Error struct {
+	Type   string
+	Status int
+	Title  string
+	//Detail explica esta ocorrência do problema para o cliente.
+	Detail string
+	//Extensions são membros adicionais do problema (ex.: "rule" em um 403 das políticas).
+	Extensions map[string]interface{}
+	//Err é a causa interna (ex.: um erro do driver do MongoDB). Vai para o log e nunca é mostrada em produção.
+	Err error
+}
+
+//Error retorna o detail e, se houver, a causa interna.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+//With retorna o erro com o membro adicional key, que aparece no corpo do problema ao lado de type, title e detail.
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Extensions == nil {
+		e.Extensions = map[string]interface{}{}
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+func newError(status int, problemType, title, detail string) *Error {
+	return &Error{Type: problemType, Status: status, Title: title, Detail: detail}
+}
+
+//BadRequest é uma requisição malformada: corpo que não é JSON, ID inválido ou parâmetro de consulta desconhecido (400).
+func BadRequest(detail string) *Error {
+	return newError(http.StatusBadRequest, TypeBadRequest, "Bad request", detail)
+}
+
+//Validation é um corpo bem formado que não passa nas regras de validação (400).
+func Validation(detail string) *Error {
+	return newError(http.StatusBadRequest, TypeValidation, "Validation failed", detail)
+}
+
+//Unauthorized é uma requisição sem credenciais ou com um token ou chave de API recusados (401).
+func Unauthorized(detail string) *Error {
+	return newError(http.StatusUnauthorized, TypeUnauthorized, "Unauthorized", detail)
+}
+
+//Forbidden é uma ação negada pelos scopes da chave de API ou pelas políticas (403).
+func Forbidden(detail string) *Error {
+	return newError(http.StatusForbidden, TypeForbidden, "Forbidden", detail)
+}
+
+//NotFound é um recurso inexistente (404).
+func NotFound(detail string) *Error {
+	return newError(http.StatusNotFound, TypeNotFound, "Not found", detail)
+}
+
+//Conflict é uma requisição incompatível com o estado atual do recurso (409).
+func Conflict(detail string) *Error {
+	return newError(http.StatusConflict, TypeConflict, "Conflict", detail)
+}
+
+//PreconditionFailed é um If-Match que não corresponde à versão armazenada (412).
+func PreconditionFailed(detail string) *Error {
+	return newError(http.StatusPreconditionFailed, TypePreconditionFailed, "Precondition failed", detail)
+}
+
+//UnsupportedMediaType é um corpo com um Content-Type que a rota não aceita (415).
+func UnsupportedMediaType(detail string) *Error {
+	return newError(http.StatusUnsupportedMediaType, TypeUnsupportedMediaType, "Unsupported media type", detail)
+}
+
+//IdempotencyKeyReused é um Idempotency-Key já usado com outra requisição (422).
+func IdempotencyKeyReused(detail string) *Error {
+	return newError(http.StatusUnprocessableEntity, TypeIdempotencyKeyReused, "Idempotency key reused", detail)
+}
+
+//RateLimited é um cliente que esgotou o orçamento do limitador (429).
+func RateLimited(detail string) *Error {
+	return newError(http.StatusTooManyRequests, TypeRateLimited, "Too many requests", detail)
+}
+
+//Internal é uma falha do servidor ou do banco de dados (500). A causa err vai apenas para o log;
+//em produção, o detail não a inclui.
+func Internal(err error) *Error {
+	problem := newError(http.StatusInternalServerError, TypeInternal, "Internal server error", "The server failed to process the request")
+	problem.Err = err
+	return problem
+}
+
+//Unavailable é um serviço que não pode atender agora (ex.: GET /readyz durante o encerramento ou o MongoDB fora do ar) (503).
+func Unavailable(detail string) *Error {
+	return newError(http.StatusServiceUnavailable, TypeUnavailable, "Service unavailable", detail)
+}
+
+//StatusOf retorna o status HTTP com que err é respondido pelo ErrorHandler:
+//o de um Error ou de um fiber.Error (ex.: 404 de uma rota inexistente) e 500 para os demais.
+func StatusOf(err error) int {
+	return fromError(err).Status
+}
+
+//Converte qualquer erro em um Error. Erros que não são da aplicação nem do Fiber viram Internal.
+func fromError(err error) *Error {
+	var problem *Error
+	if errors.As(err, &problem) {
+		return problem
+	}
+	var fiberErr *fiberError
+	if errors.As(err, &fiberErr) {
+		return fiberProblem(fiberErr)
+	}
+	return Internal(err)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+    "github.com/gofiber/fiber/v2"
+    "github.com/nathanfernande/golang-mongodb-api/controllers"
+    "github.com/nathanfernande/golang-mongodb-api/middleware"
+    "github.com/nathanfernande/golang-mongodb-api/models"
+    "github.com/nathanfernande/golang-mongodb-api/policy"
+)
+
+//Rotas administrativas de chaves de API. Chaves de API só podem usá-las com o scope apikeys:admin
+//e tokens JWT precisam que as políticas permitam a ação apikeys:admin.
+func APIKeyRoute(app *fiber.App, apiKeyController *controllers.APIKeyController, authenticate, rateLimit fiber.Handler, authorize func(action string) fiber.Handler) {
+    scope := middleware.RequireScope(models.ScopeAPIKeysAdmin)
+    admin := authorize(policy.ActionAPIKeysAdmin)
+
+    app.Post("/admin/api-keys", authenticate, rateLimit, scope, admin, apiKeyController.CreateAPIKey)
+    app.Get("/admin/api-keys", authenticate, rateLimit, scope, admin, apiKeyController.GetAllAPIKeys)
+    app.Delete("/admin/api-keys/:keyId", authenticate, rateLimit, scope, admin, apiKeyController.RevokeAPIKey)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+    "io/fs"
+    "net/http"
+
+    "github.com/gofiber/fiber/v2"
+    "github.com/gofiber/fiber/v2/middleware/filesystem"
+)
+
+//Rotas da documentação da API: a especificação OpenAPI (ex.: openapi.Spec) em GET /openapi.json,
+//a documentação interativa (ex.: openapi.DocsPage) em GET /docs e os arquivos que ela carrega (ex.: openapi.DocsAssets)
+//em GET /docs/assets/. Assim como as rotas de saúde, não exigem autenticação.
+//Os arquivos estáticos não aparecem em openapi/openapi.yaml: não fazem parte da API.
+func DocsRoute(app *fiber.App, spec, docsPage []byte, assets fs.FS) {
+    app.Get("/openapi.json", func(c *fiber.Ctx) error {
+        c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+        return c.Send(spec)
+    })
+    app.Get("/docs", func(c *fiber.Ctx) error {
+        c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+        return c.Send(docsPage)
+    })
+    app.Use("/docs/assets", filesystem.New(filesystem.Config{Root: http.FS(assets)}))
+}
@@ -0,0 +1,65 @@
+package routes
+
+import (
+    "io"
+    "net/http/httptest"
+    "regexp"
+    "strings"
+    "testing"
+
+    "github.com/gofiber/fiber/v2"
+    "github.com/nathanfernande/golang-mongodb-api/openapi"
+)
+
+//Atributos href e src da página de documentação.
+var docsPageLinks = regexp.MustCompile(`(?:href|src)="([^"]+)"`)
+
+func getDocs(t *testing.T, app *fiber.App, target string) (int, string, string) {
+    t.Helper()
+    resp, err := app.Test(httptest.NewRequest("GET", target, nil), -1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), string(body)
+}
+
+//A página /docs só carrega arquivos servidos pela própria API, e todos eles existem.
+func TestDocsPageUsesEmbeddedAssets(t *testing.T) {
+    spec, err := openapi.Spec()
+    if err != nil {
+        t.Fatal(err)
+    }
+    app := fiber.New()
+    DocsRoute(app, spec, openapi.DocsPage(), openapi.DocsAssets())
+
+    status, contentType, page := getDocs(t, app, "/docs")
+    if status != 200 || !strings.HasPrefix(contentType, fiber.MIMETextHTML) {
+        t.Fatalf("GET /docs = %d %s", status, contentType)
+    }
+    links := docsPageLinks.FindAllStringSubmatch(page, -1)
+    if len(links) == 0 {
+        t.Fatal("the docs page loads no assets")
+    }
+    for _, link := range links {
+        target := link[1]
+        if !strings.HasPrefix(target, "/docs/assets/") {
+            t.Errorf("the docs page loads %s, which is not served by the API", target)
+            continue
+        }
+        if status, _, body := getDocs(t, app, target); status != 200 || body == "" {
+            t.Errorf("GET %s = %d with %d bytes", target, status, len(body))
+        }
+    }
+
+    if status, _, _ := getDocs(t, app, "/docs/assets/NOTICE"); status != 404 {
+        t.Errorf("GET /docs/assets/NOTICE = %d, want 404: only the assets are embedded", status)
+    }
+    if status, contentType, _ := getDocs(t, app, "/openapi.json"); status != 200 || !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+        t.Errorf("GET /openapi.json = %d %s", status, contentType)
+    }
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+    "github.com/gofiber/fiber/v2"
+    "github.com/nathanfernande/golang-mongodb-api/controllers"
+)
+
+//Rotas de saúde usadas pelo orquestrador. Não exigem autenticação nem consomem o orçamento do limitador de requisições,
+//já que são chamadas com frequência e por quem não tem credenciais.
+func HealthRoute(app *fiber.App, healthController *controllers.HealthController) {
+    app.Get("/healthz", healthController.Liveness)
+    app.Get("/readyz", healthController.Readiness)
+}
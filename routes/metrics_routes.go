@@ -0,0 +1,14 @@
+package routes
+
+import (
+    "net/http"
+
+    "github.com/gofiber/fiber/v2"
+    "github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+//Rota das métricas no formato de texto do Prometheus (ex.: metrics.Metrics.Handler).
+//Assim como as rotas de saúde, não exige autenticação: o acesso deve ser restrito na rede, pelo scraper.
+func MetricsRoute(app *fiber.App, handler http.Handler) {
+    app.Get("/metrics", adaptor.HTTPHandler(handler))
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+    "encoding/json"
+    "net/http"
+    "regexp"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "github.com/nathanfernande/golang-mongodb-api/controllers"
+    "github.com/nathanfernande/golang-mongodb-api/openapi"
+)
+
+//Parâmetros de rota do Fiber (:userId), que no OpenAPI são escritos entre chaves ({userId}).
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+//Métodos que podem aparecer em um path item do OpenAPI; as demais chaves (ex.: parameters) não são operações.
+var specMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+//Toda rota registrada por Register precisa estar documentada em openapi/openapi.yaml, e toda operação documentada precisa existir.
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+    app := fiber.New()
+    pass := func(c *fiber.Ctx) error { return c.Next() }
+    Register(app, Dependencies{
+        Health:       controllers.NewHealthController(time.Second),
+        Metrics:      http.NotFoundHandler(),
+        DocsAssets:   openapi.DocsAssets(),
+        Users:        controllers.NewUserController(nil, nil, 0, controllers.Timeouts{}),
+        APIKeys:      controllers.NewAPIKeyController(nil, 0),
+        Authenticate: pass,
+        RateLimit:    pass,
+        Authorize:    func(string) fiber.Handler { return pass },
+        Idempotent:   pass,
+    })
+
+    spec, err := openapi.Spec()
+    if err != nil {
+        t.Fatal(err)
+    }
+    var document struct {
+        Paths map[string]map[string]json.RawMessage `json:"paths"`
+    }
+    if err := json.Unmarshal(spec, &document); err != nil {
+        t.Fatal(err)
+    }
+
+    registered := map[string]bool{}
+    for _, route := range app.GetRoutes(true) {
+        //O Fiber registra HEAD junto com cada GET.
+        if route.Method == fiber.MethodHead {
+            continue
+        }
+        path := routeParam.ReplaceAllString(route.Path, "{$1}")
+        method := strings.ToLower(route.Method)
+        registered[method+" "+path] = true
+        if _, ok := document.Paths[path][method]; !ok {
+            t.Errorf("%s %s is registered but has no entry in openapi/openapi.yaml", route.Method, path)
+        }
+    }
+    for path, item := range document.Paths {
+        for _, method := range specMethods {
+            if _, ok := item[method]; ok && !registered[method+" "+path] {
+                t.Errorf("%s %s is documented in openapi/openapi.yaml but not registered", strings.ToUpper(method), path)
+            }
+        }
+    }
+}
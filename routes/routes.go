@@ -0,0 +1,36 @@
+package routes
+
+import (
+    "io/fs"
+    "net/http"
+
+    "github.com/gofiber/fiber/v2"
+    "github.com/nathanfernande/golang-mongodb-api/controllers"
+)
+
+//Dependencies são os controllers, os handlers e os middlewares usados pelas rotas, montados por App.setup.
+//Os middlewares são descritos em UserRoute.
+type Dependencies struct {
+    Health     *controllers.HealthController
+    Metrics    http.Handler
+    Spec       []byte
+    DocsPage   []byte
+    DocsAssets fs.FS
+    Users      *controllers.UserController
+    APIKeys    *controllers.APIKeyController
+
+    Authenticate fiber.Handler
+    RateLimit    fiber.Handler
+    Authorize    func(action string) fiber.Handler
+    Idempotent   fiber.Handler
+}
+
+//Register registra todas as rotas da API. É o único lugar em que as rotas são registradas: App.setup e
+//TestRoutesMatchOpenAPISpec chamam Register, então uma rota nova aparece nos dois sem passos extras.
+func Register(app *fiber.App, deps Dependencies) {
+    HealthRoute(app, deps.Health)
+    MetricsRoute(app, deps.Metrics)
+    DocsRoute(app, deps.Spec, deps.DocsPage, deps.DocsAssets)
+    UserRoute(app, deps.Users, deps.Authenticate, deps.RateLimit, deps.Authorize, deps.Idempotent)
+    APIKeyRoute(app, deps.APIKeys, deps.Authenticate, deps.RateLimit, deps.Authorize)
+}
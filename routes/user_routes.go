@@ -3,13 +3,39 @@ package routes
 import (
     "github.com/gofiber/fiber/v2"
     "github.com/nathanfernande/golang-mongodb-api/controllers"
+    "github.com/nathanfernande/golang-mongodb-api/middleware"
+    "github.com/nathanfernande/golang-mongodb-api/models"
+    "github.com/nathanfernande/golang-mongodb-api/policy"
 )
 
-func UserRoute(app *fiber.App) {
+//authenticate é o middleware de autenticação (ex.: middleware.Authenticate envolvido por middleware.LimitByIP) executado antes de cada rota de usuários.
+//Depois dele, rateLimit (ex.: middleware.RateLimiter) consome o orçamento do cliente, middleware.RequireScope confere
+//o scope exigido pela rota quando a requisição usa uma chave de API e authorize (ex.: middleware.Authorizer)
+//avalia as políticas da ação quando a requisição usa um token JWT.
+//idempotent (ex.: middleware.Idempotency) faz com que as repetições de POST /user com o mesmo Idempotency-Key não criem usuários duplicados.
+//Cada rota precisa de uma entrada em openapi/openapi.yaml (ver TestRoutesMatchOpenAPISpec).
+func UserRoute(app *fiber.App, userController *controllers.UserController, authenticate, rateLimit fiber.Handler, authorize func(action string) fiber.Handler, idempotent fiber.Handler) {
+    read := middleware.RequireScope(models.ScopeUsersRead)
+    write := middleware.RequireScope(models.ScopeUsersWrite)
+    remove := middleware.RequireScope(models.ScopeUsersDelete)
+    //o lote pode ter criações, edições e exclusões, então exige os dois scopes
+    bulk := middleware.RequireScope(models.ScopeUsersWrite, models.ScopeUsersDelete)
+
     //todas as rotas relacionadas aos usuarios estarão aqui
-	app.Post("/user", controllers.CreateUser)
-    app.Get("/user/:userId", controllers.GetAUser)
-    app.Put("/user/:userId", controllers.EditAUser)
-    app.Delete("/user/:userId", controllers.DeleteAUser)
-    app.Get("/users", controllers.GetAllUsers)
-}
\ No newline at end of file
+    app.Post("/user", authenticate, rateLimit, write, authorize(policy.ActionUsersCreate), idempotent, userController.CreateUser)
+    app.Get("/user/:userId", authenticate, rateLimit, read, authorize(policy.ActionUsersRead), userController.GetAUser)
+    app.Put("/user/:userId", authenticate, rateLimit, write, authorize(policy.ActionUsersUpdate), userController.EditAUser)
+    app.Patch("/user/:userId", authenticate, rateLimit, write, authorize(policy.ActionUsersUpdate), userController.PatchAUser)
+    app.Delete("/user/:userId", authenticate, rateLimit, remove, authorize(policy.ActionUsersDelete), userController.DeleteAUser)
+    app.Post("/user/:userId/restore", authenticate, rateLimit, write, authorize(policy.ActionUsersRestore), userController.RestoreAUser)
+    app.Get("/user/:userId/history", authenticate, rateLimit, read, authorize(policy.ActionUsersRead), userController.GetUserHistory)
+    app.Get("/users", authenticate, rateLimit, read, authorize(policy.ActionUsersList), userController.GetAllUsers)
+    app.Get("/users/search", authenticate, rateLimit, read, authorize(policy.ActionUsersList), userController.SearchUsers)
+    app.Get("/users/stream", authenticate, rateLimit, read, authorize(policy.ActionUsersList), userController.StreamUsers)
+    app.Post("/users/bulk", authenticate, rateLimit, bulk, authorize(policy.ActionUsersBulk), userController.BulkUsers)
+    app.Get("/users/export.csv", authenticate, rateLimit, read, authorize(policy.ActionUsersList), userController.ExportUsersCSV)
+    app.Post("/users/import", authenticate, rateLimit, write, authorize(policy.ActionUsersImport), userController.ImportUsersCSV)
+
+    //rotas administrativas
+    app.Post("/admin/users/purge", authenticate, rateLimit, remove, authorize(policy.ActionUsersPurge), userController.PurgeUsers)
+}
@@ -0,0 +1,55 @@
+package stores
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//ErrAPIKeyNotFound é retornado quando nenhuma chave de API tem o ID ou o hash informado.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+//APIKeyStore define as operações de persistência das chaves de API.
+//Assim como UserStore, tem uma implementação no MongoDB (MongoAPIKeyStore) e outra em memória (MemoryAPIKeyStore).
+type APIKeyStore interface {
+	//Create grava uma nova chave.
+	Create(ctx context.Context, key models.APIKey) error
+	//GetByHash retorna a chave com o hash informado, inclusive se estiver expirada ou revogada.
+	//Retorna ErrAPIKeyNotFound se ela não existir.
+	GetByHash(ctx context.Context, hash string) (models.APIKey, error)
+	//List retorna todas as chaves, das mais novas para as mais antigas.
+	List(ctx context.Context) ([]models.APIKey, error)
+	//Revoke marca a chave como revogada em revokedAt e retorna a chave atualizada.
+	//Revogar uma chave já revogada não altera a data original. Retorna ErrAPIKeyNotFound se ela não existir.
+	Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (models.APIKey, error)
+	//TouchLastUsed registra o último uso da chave.
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+}
+
+//Prefixo de todas as chaves de API, para que sejam fáceis de reconhecer (ex.: em ferramentas que procuram segredos vazados).
+const apiKeyPrefix = "uak_"
+
+//NewAPIKeySecret gera uma nova chave de API aleatória (256 bits) e retorna a chave completa,
+//que deve ser entregue ao cliente uma única vez, e o prefixo usado para identificá-la nas listagens.
+func NewAPIKeySecret() (secret, prefix string, err error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	secret = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return secret, secret[:len(apiKeyPrefix)+8], nil
+}
+
+//HashAPIKey retorna o hash SHA-256 (hex) guardado no lugar da chave.
+//Como as chaves são aleatórias e longas, um hash rápido sem salt basta e permite buscar a chave pelo hash.
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
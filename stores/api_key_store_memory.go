@@ -0,0 +1,84 @@
+package stores
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//MemoryAPIKeyStore implementa APIKeyStore guardando as chaves em um mapa protegido por mutex.
+//Serve para testes e desenvolvimento local, sem depender de um MongoDB.
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[primitive.ObjectID]models.APIKey
+}
+
+//NewMemoryAPIKeyStore cria um MemoryAPIKeyStore vazio.
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: map[primitive.ObjectID]models.APIKey{}}
+}
+
+func (s *MemoryAPIKeyStore) Create(ctx context.Context, key models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.Id] = key
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) GetByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, ErrAPIKeyNotFound
+}
+
+func (s *MemoryAPIKeyStore) List(ctx context.Context) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	//Das mais novas para as mais antigas, como o MongoAPIKeyStore.
+	sort.Slice(keys, func(i, j int) bool {
+		return compareIds(keys[i].Id, keys[j].Id) > 0
+	})
+	return keys, nil
+}
+
+func (s *MemoryAPIKeyStore) Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &revokedAt
+		s.keys[id] = key
+	}
+	return key, nil
+}
+
+func (s *MemoryAPIKeyStore) TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[id]; ok {
+		key.LastUsedAt = &usedAt
+		s.keys[id] = key
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//MongoAPIKeyStore implementa APIKeyStore sobre uma coleção do MongoDB.
+type MongoAPIKeyStore struct {
+	collection *mongo.Collection
+}
+
+//NewMongoAPIKeyStore cria um MongoAPIKeyStore que usa a coleção informada (normalmente obtida com configs.GetCollection).
+func NewMongoAPIKeyStore(collection *mongo.Collection) *MongoAPIKeyStore {
+	return &MongoAPIKeyStore{collection: collection}
+}
+
+//EnsureIndexes cria o índice único sobre o hash, usado para encontrar a chave a cada requisição.
+//Deve ser chamado na inicialização da aplicação.
+func (s *MongoAPIKeyStore) EnsureIndexes(ctx context.Context) error {
+	hashIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetName("api_key_hash").SetUnique(true),
+	}
+	_, err := s.collection.Indexes().CreateOne(ctx, hashIndex)
+	return err
+}
+
+func (s *MongoAPIKeyStore) Create(ctx context.Context, key models.APIKey) error {
+	_, err := s.collection.InsertOne(ctx, key)
+	return err
+}
+
+func (s *MongoAPIKeyStore) GetByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := s.collection.FindOne(ctx, bson.D{{Key: "hash", Value: hash}}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (s *MongoAPIKeyStore) List(ctx context.Context) ([]models.APIKey, error) {
+	results, err := s.collection.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "id", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := results.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *MongoAPIKeyStore) Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (models.APIKey, error) {
+	//Só altera chaves ainda não revogadas, para preservar a data da primeira revogação.
+	filter := bson.D{{Key: "id", Value: id}, {Key: "revokedAt", Value: nil}}
+	update := bson.M{"$set": bson.M{"revokedAt": revokedAt}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var key models.APIKey
+	err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		//A chave não existe ou já estava revogada.
+		err = s.collection.FindOne(ctx, bson.D{{Key: "id", Value: id}}).Decode(&key)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.APIKey{}, ErrAPIKeyNotFound
+		}
+	}
+	return key, err
+}
+
+func (s *MongoAPIKeyStore) TouchLastUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	_, err := s.collection.UpdateOne(ctx, bson.D{{Key: "id", Value: id}}, bson.M{"$set": bson.M{"lastUsedAt": usedAt}})
+	return err
+}
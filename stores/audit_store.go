@@ -0,0 +1,51 @@
+package stores
+
+import (
+	"context"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//AuditStore define as operações de persistência da trilha de auditoria de usuários.
+//Assim como UserStore, tem uma implementação no MongoDB (MongoAuditStore) e outra em memória (MemoryAuditStore).
+type AuditStore interface {
+	//Record grava um novo registro de auditoria.
+	Record(ctx context.Context, entry models.AuditEntry) error
+	//History retorna uma página dos registros de um usuário, do mais recente para o mais antigo.
+	//Retorna ErrInvalidCursor se o cursor não puder ser usado.
+	History(ctx context.Context, userId primitive.ObjectID, query AuditQuery) (AuditPage, error)
+}
+
+//AuditQuery descreve uma página do histórico de um usuário.
+type AuditQuery struct {
+	//Limit é o número máximo de registros na página. Zero usa DefaultPageSize.
+	Limit int
+	//Cursor é o valor opaco de AuditPage.NextCursor da página anterior. Vazio começa do registro mais recente.
+	Cursor string
+}
+
+//AuditPage é o resultado de uma consulta paginada ao histórico.
+type AuditPage struct {
+	Entries []models.AuditEntry
+	//NextCursor deve ser enviado na próxima consulta para obter a página seguinte. Vazio quando não há mais páginas.
+	NextCursor string
+}
+
+//O histórico é sempre ordenado pelo ID do registro em ordem decrescente (ObjectIDs crescem com o tempo),
+//então o cursor usa a mesma codificação da listagem de usuários ordenada por "-id".
+var auditSort = UserSort{Descending: true}
+
+//Retorna o limite efetivo da consulta, aplicando o padrão e o teto do servidor.
+func (q AuditQuery) pageSize() int {
+	return UserQuery{Limit: q.Limit}.pageSize()
+}
+
+//Monta a página a partir de até pageSize+1 registros: o item excedente indica que existe uma próxima página.
+func newAuditPage(entries []models.AuditEntry, pageSize int) AuditPage {
+	if len(entries) <= pageSize {
+		return AuditPage{Entries: entries}
+	}
+	entries = entries[:pageSize]
+	return AuditPage{Entries: entries, NextCursor: cursorData{Sort: auditSort.String(), Id: entries[len(entries)-1].Id}.encode()}
+}
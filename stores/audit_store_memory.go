@@ -0,0 +1,58 @@
+package stores
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//MemoryAuditStore implementa AuditStore guardando os registros em memória.
+//Serve para testes e desenvolvimento local, sem depender de um MongoDB.
+type MemoryAuditStore struct {
+	mu      sync.RWMutex
+	entries []models.AuditEntry
+}
+
+//NewMemoryAuditStore cria um MemoryAuditStore vazio.
+func NewMemoryAuditStore() *MemoryAuditStore {
+	return &MemoryAuditStore{}
+}
+
+func (s *MemoryAuditStore) Record(ctx context.Context, entry models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *MemoryAuditStore) History(ctx context.Context, userId primitive.ObjectID, query AuditQuery) (AuditPage, error) {
+	cursor, ok, err := decodeCursor(query.Cursor, auditSort)
+	if err != nil {
+		return AuditPage{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	for _, entry := range s.entries {
+		if entry.UserId != userId || (ok && compareIds(entry.Id, cursor.Id) >= 0) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	//Ordena do mais recente para o mais antigo, como o MongoAuditStore.
+	sort.Slice(entries, func(i, j int) bool {
+		return compareIds(entries[i].Id, entries[j].Id) > 0
+	})
+
+	pageSize := query.pageSize()
+	if len(entries) > pageSize+1 {
+		entries = entries[:pageSize+1]
+	}
+	return newAuditPage(entries, pageSize), nil
+}
@@ -0,0 +1,63 @@
+package stores
+
+import (
+	"context"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//MongoAuditStore implementa AuditStore sobre uma coleção do MongoDB separada da coleção de usuários.
+type MongoAuditStore struct {
+	collection *mongo.Collection
+}
+
+//NewMongoAuditStore cria um MongoAuditStore que usa a coleção informada (normalmente obtida com configs.GetCollection).
+func NewMongoAuditStore(collection *mongo.Collection) *MongoAuditStore {
+	return &MongoAuditStore{collection: collection}
+}
+
+//EnsureIndexes cria o índice usado pela consulta do histórico, se ainda não existir.
+//Deve ser chamado na inicialização da aplicação.
+func (s *MongoAuditStore) EnsureIndexes(ctx context.Context) error {
+	historyIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "id", Value: -1}},
+		Options: options.Index().SetName("audit_user_history"),
+	}
+	_, err := s.collection.Indexes().CreateOne(ctx, historyIndex)
+	return err
+}
+
+func (s *MongoAuditStore) Record(ctx context.Context, entry models.AuditEntry) error {
+	_, err := s.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (s *MongoAuditStore) History(ctx context.Context, userId primitive.ObjectID, query AuditQuery) (AuditPage, error) {
+	cursor, ok, err := decodeCursor(query.Cursor, auditSort)
+	if err != nil {
+		return AuditPage{}, err
+	}
+	filter := bson.D{{Key: "userId", Value: userId}}
+	if ok {
+		filter = append(filter, bson.E{Key: "id", Value: bson.M{"$lt": cursor.Id}})
+	}
+
+	//Busca um registro a mais que o tamanho da página para saber se existe uma próxima página.
+	pageSize := query.pageSize()
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: -1}}).SetLimit(int64(pageSize + 1))
+	results, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return AuditPage{}, err
+	}
+	defer results.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := results.All(ctx, &entries); err != nil {
+		return AuditPage{}, err
+	}
+	return newAuditPage(entries, pageSize), nil
+}
@@ -0,0 +1,36 @@
+package stores
+
+import (
+	"context"
+	"time"
+)
+
+//IdempotencyRecord guarda a resposta de uma requisição feita com o cabeçalho Idempotency-Key.
+//Enquanto a primeira requisição está em andamento, o registro fica pendente (Completed = false).
+type IdempotencyRecord struct {
+	//Key identifica o cliente e a chave enviada por ele, para que clientes diferentes não compartilhem respostas.
+	Key string `bson:"key"`
+	//Fingerprint é o hash do método, do caminho e do corpo da requisição original.
+	Fingerprint string `bson:"fingerprint"`
+	Completed   bool   `bson:"completed"`
+	//Status, Headers e Body são a resposta original, devolvida sem alterações nas repetições.
+	Status    int               `bson:"status,omitempty"`
+	Headers   map[string]string `bson:"headers,omitempty"`
+	Body      []byte            `bson:"body,omitempty"`
+	CreatedAt time.Time         `bson:"createdAt"`
+	//ExpiresAt é quando o registro deixa de valer: pouco depois do início enquanto está pendente,
+	//para que uma requisição interrompida não bloqueie a chave, e o TTL configurado depois de completo.
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+//IdempotencyStore guarda as respostas das requisições com Idempotency-Key.
+//Tem uma implementação em memória (MemoryIdempotencyStore) e outra no MongoDB (MongoIdempotencyStore).
+type IdempotencyStore interface {
+	//Begin reserva a chave com um registro pendente. Se já houver um registro válido para a chave,
+	//ele é retornado com created = false e nada é gravado.
+	Begin(ctx context.Context, record IdempotencyRecord) (existing IdempotencyRecord, created bool, err error)
+	//Complete grava a resposta no registro pendente da chave.
+	Complete(ctx context.Context, record IdempotencyRecord) error
+	//Release apaga o registro pendente da chave, para que a requisição possa ser repetida (ex.: depois de um erro 5xx).
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,55 @@
+package stores
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//MemoryIdempotencyStore implementa IdempotencyStore em memória. Os registros valem só para a instância atual.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+//NewMemoryIdempotencyStore cria um MemoryIdempotencyStore vazio.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: map[string]IdempotencyRecord{}}
+}
+
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//Remove os registros expirados, como faria o índice TTL do MongoDB.
+	now := time.Now()
+	for key, stored := range s.records {
+		if !now.Before(stored.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+
+	if existing, ok := s.records[record.Key]; ok {
+		return existing, false, nil
+	}
+	s.records[record.Key] = record
+	return record, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.Key] = record
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.records[key]; ok && !stored.Completed {
+		delete(s.records, key)
+	}
+	return nil
+}
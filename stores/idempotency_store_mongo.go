@@ -0,0 +1,66 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//MongoIdempotencyStore implementa IdempotencyStore sobre uma coleção do MongoDB, compartilhada por todas as réplicas da API.
+type MongoIdempotencyStore struct {
+	collection *mongo.Collection
+}
+
+//NewMongoIdempotencyStore cria um MongoIdempotencyStore que usa a coleção informada (normalmente obtida com configs.GetCollection).
+func NewMongoIdempotencyStore(collection *mongo.Collection) *MongoIdempotencyStore {
+	return &MongoIdempotencyStore{collection: collection}
+}
+
+//EnsureIndexes cria o índice único sobre a chave, que garante que só uma requisição reserve cada chave,
+//e o índice TTL que apaga os registros expirados. Deve ser chamado na inicialização da aplicação.
+func (s *MongoIdempotencyStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetName("idempotency_key").SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("idempotency_ttl").SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (s *MongoIdempotencyStore) Begin(ctx context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	//O índice TTL apaga os registros expirados com atraso de até um minuto, então um registro expirado
+	//ainda presente é substituído. O upsert só insere quando não há registro válido; com um registro válido,
+	//o filtro não encontra nada e o upsert tenta inserir uma chave repetida, o que o índice único impede.
+	filter := bson.D{{Key: "key", Value: record.Key}, {Key: "expiresAt", Value: bson.M{"$lte": time.Now()}}}
+	_, err := s.collection.ReplaceOne(ctx, filter, record, options.Replace().SetUpsert(true))
+	if err == nil {
+		return record, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return IdempotencyRecord{}, false, err
+	}
+
+	var existing IdempotencyRecord
+	err = s.collection.FindOne(ctx, bson.D{{Key: "key", Value: record.Key}}).Decode(&existing)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		//O registro expirou e foi apagado entre as duas operações; tenta reservar de novo.
+		return s.Begin(ctx, record)
+	}
+	if err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (s *MongoIdempotencyStore) Complete(ctx context.Context, record IdempotencyRecord) error {
+	_, err := s.collection.ReplaceOne(ctx, bson.D{{Key: "key", Value: record.Key}}, record)
+	return err
+}
+
+func (s *MongoIdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.D{{Key: "key", Value: key}, {Key: "completed", Value: false}})
+	return err
+}
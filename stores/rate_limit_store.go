@@ -0,0 +1,60 @@
+package stores
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+//RateLimit é o orçamento de um balde de tokens (token bucket): até Requests requisições de uma vez,
+//com os tokens sendo repostos continuamente até encher o balde de novo em Window.
+type RateLimit struct {
+	Requests int64
+	Window   time.Duration
+}
+
+//Tokens repostos por segundo.
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+//RateLimitResult é o estado do balde depois de uma tentativa de consumir um token.
+type RateLimitResult struct {
+	Allowed bool
+	//Remaining é o número de requisições que ainda podem ser feitas imediatamente.
+	Remaining int64
+	//RetryAfter é o tempo até haver um token disponível; zero quando a requisição foi permitida.
+	RetryAfter time.Duration
+	//ResetAfter é o tempo até o balde estar cheio de novo.
+	ResetAfter time.Duration
+}
+
+//RateLimitStore guarda o estado dos baldes de tokens de cada cliente.
+//Tem uma implementação em memória (MemoryRateLimitStore), para uma única instância, e outra no MongoDB
+//(MongoRateLimitStore), para que várias réplicas da API compartilhem os mesmos orçamentos.
+type RateLimitStore interface {
+	//Take repõe os tokens do balde key desde o último acesso e tenta consumir um.
+	//Um balde que ainda não existe começa cheio.
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+//Tokens do balde depois de elapsed sem acessos, limitados à capacidade.
+func refillTokens(tokens float64, elapsed time.Duration, limit RateLimit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.rate())
+}
+
+//Monta o resultado a partir dos tokens que sobraram no balde depois da tentativa.
+func newRateLimitResult(limit RateLimit, tokens float64, allowed bool) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:    allowed,
+		Remaining:  int64(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(limit.Requests) - tokens) / limit.rate() * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package stores
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//Quantidade de chamadas a Take entre duas limpezas dos baldes que já voltaram a ficar cheios.
+const rateLimitSweepEvery = 1000
+
+//MemoryRateLimitStore implementa RateLimitStore em memória. Os orçamentos valem só para a instância atual.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]tokenBucket
+	takes   int
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     RateLimit
+}
+
+//NewMemoryRateLimitStore cria um MemoryRateLimitStore vazio.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]tokenBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = tokenBucket{tokens: float64(limit.Requests), updatedAt: now}
+	}
+	bucket.tokens = refillTokens(bucket.tokens, now.Sub(bucket.updatedAt), limit)
+	bucket.updatedAt = now
+	bucket.limit = limit
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	s.buckets[key] = bucket
+	return newRateLimitResult(limit, bucket.tokens, allowed), nil
+}
+
+//Remove de tempos em tempos os baldes que já estariam cheios: eles são equivalentes a um balde novo,
+//e assim o mapa não cresce com cada cliente que já passou pela API.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	s.takes++
+	if s.takes < rateLimitSweepEvery {
+		return
+	}
+	s.takes = 0
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updatedAt) >= bucket.limit.Window {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package stores
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//MongoRateLimitStore implementa RateLimitStore sobre uma coleção do MongoDB, compartilhada por todas as réplicas da API.
+//Cada balde é um documento {key, tokens, updatedAt, expiresAt}, atualizado de forma atômica a cada requisição.
+type MongoRateLimitStore struct {
+	collection *mongo.Collection
+}
+
+//NewMongoRateLimitStore cria um MongoRateLimitStore que usa a coleção informada (normalmente obtida com configs.GetCollection).
+func NewMongoRateLimitStore(collection *mongo.Collection) *MongoRateLimitStore {
+	return &MongoRateLimitStore{collection: collection}
+}
+
+//EnsureIndexes cria o índice único sobre a chave do balde e o índice TTL que apaga os baldes
+//que já voltaram a ficar cheios. Deve ser chamado na inicialização da aplicação.
+func (s *MongoRateLimitStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetName("rate_limit_key").SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("rate_limit_ttl").SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (s *MongoRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	//A reposição e o consumo são feitos em um único update com pipeline, usando o relógio do próprio MongoDB ($$NOW),
+	//para que réplicas com relógios diferentes vejam o mesmo balde. Um balde novo (upsert) começa cheio.
+	capacity := float64(limit.Requests)
+	elapsedSeconds := bson.M{"$max": bson.A{0, bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updatedAt", "$$NOW"}}}}, 1000}}}}
+	refilled := bson.M{"$min": bson.A{capacity, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$tokens", capacity}}, bson.M{"$multiply": bson.A{elapsedSeconds, limit.rate()}}}}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled, "updatedAt": "$$NOW"}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+			"tokens":  bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$tokens", 1}}, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			//Depois de Window sem acessos o balde estaria cheio, então o documento pode ser apagado pelo índice TTL.
+			"expiresAt": bson.M{"$add": bson.A{"$$NOW", limit.Window.Milliseconds()}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	err := s.collection.FindOneAndUpdate(ctx, bson.D{{Key: "key", Value: key}}, pipeline, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		//Duas réplicas criaram o mesmo balde ao mesmo tempo; na segunda tentativa o documento já existe.
+		err = s.collection.FindOneAndUpdate(ctx, bson.D{{Key: "key", Value: key}}, pipeline, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return newRateLimitResult(limit, bucket.Tokens, bucket.Allowed), nil
+}
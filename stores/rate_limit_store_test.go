@@ -0,0 +1,104 @@
+package stores
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefillTokens(t *testing.T) {
+	limit := RateLimit{Requests: 60, Window: time.Minute}
+	tests := []struct {
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{0, 0, 0},
+		//Um token por segundo.
+		{0, time.Second, 1},
+		{2.5, 1500 * time.Millisecond, 4},
+		{10, 30 * time.Second, 40},
+		//O balde não passa da capacidade.
+		{59, 10 * time.Second, 60},
+		{0, time.Hour, 60},
+		//Um relógio que volta no tempo não repõe nem retira tokens.
+		{5, -time.Minute, 5},
+	}
+	for _, test := range tests {
+		if got := refillTokens(test.tokens, test.elapsed, limit); got != test.want {
+			t.Errorf("refillTokens(%v, %v) = %v, want %v", test.tokens, test.elapsed, got, test.want)
+		}
+	}
+}
+
+func TestNewRateLimitResult(t *testing.T) {
+	limit := RateLimit{Requests: 10, Window: 10 * time.Second}
+	tests := []struct {
+		tokens  float64
+		allowed bool
+		want    RateLimitResult
+	}{
+		{9, true, RateLimitResult{Allowed: true, Remaining: 9, ResetAfter: time.Second}},
+		{2.5, true, RateLimitResult{Allowed: true, Remaining: 2, ResetAfter: 7500 * time.Millisecond}},
+		{0, true, RateLimitResult{Allowed: true, Remaining: 0, ResetAfter: 10 * time.Second}},
+		//Sem token, RetryAfter é o tempo até o próximo token.
+		{0.25, false, RateLimitResult{Allowed: false, Remaining: 0, RetryAfter: 750 * time.Millisecond, ResetAfter: 9750 * time.Millisecond}},
+	}
+	for _, test := range tests {
+		if got := newRateLimitResult(limit, test.tokens, test.allowed); got != test.want {
+			t.Errorf("newRateLimitResult(%v, %v) = %+v, want %+v", test.tokens, test.allowed, got, test.want)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	//Uma janela longa faz a reposição durante o teste ser desprezível.
+	limit := RateLimit{Requests: 3, Window: time.Hour}
+
+	for i := int64(2); i >= 0; i-- {
+		result, err := store.Take(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Errorf("take %d = %+v, want allowed with %d remaining", 3-i, result, i)
+		}
+	}
+
+	result, err := store.Take(context.Background(), "client", limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed || result.Remaining != 0 {
+		t.Errorf("take after the budget = %+v, want denied", result)
+	}
+	//Um token a cada 20 minutos.
+	if result.RetryAfter <= 19*time.Minute || result.RetryAfter > 20*time.Minute {
+		t.Errorf("RetryAfter = %v, want about 20m", result.RetryAfter)
+	}
+	if result.ResetAfter <= 59*time.Minute || result.ResetAfter > time.Hour {
+		t.Errorf("ResetAfter = %v, want about 1h", result.ResetAfter)
+	}
+
+	//Cada chave tem o próprio balde, que começa cheio.
+	if result, _ := store.Take(context.Background(), "other", limit); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("take on another key = %+v", result)
+	}
+}
+
+func TestMemoryRateLimitStoreRefills(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Requests: 1, Window: 50 * time.Millisecond}
+
+	if result, _ := store.Take(context.Background(), "client", limit); !result.Allowed {
+		t.Fatalf("first take = %+v", result)
+	}
+	if result, _ := store.Take(context.Background(), "client", limit); result.Allowed {
+		t.Fatalf("second take = %+v, want denied", result)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if result, _ := store.Take(context.Background(), "client", limit); !result.Allowed {
+		t.Errorf("take after the window = %+v, want allowed", result)
+	}
+}
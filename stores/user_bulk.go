@@ -0,0 +1,71 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+)
+
+//ErrBulkSkipped é o erro de uma operação em lote que não foi executada porque, no modo ordenado,
+//uma operação anterior falhou.
+var ErrBulkSkipped = errors.New("skipped because an earlier operation failed")
+
+//BulkOp é o tipo de uma operação em lote.
+type BulkOp string
+
+const (
+	BulkCreate BulkOp = "create"
+	BulkUpdate BulkOp = "update"
+	BulkDelete BulkOp = "delete"
+)
+
+//BulkOperation é uma operação de UserStore.BulkWrite.
+type BulkOperation struct {
+	Op BulkOp
+	//User traz os campos do usuário. Em BulkCreate o Id já deve estar preenchido;
+	//em BulkUpdate e BulkDelete, User.Id identifica o usuário (e em BulkDelete os demais campos são ignorados).
+	User models.User
+	//ExpectedVersion tem o mesmo significado que em Update e Delete. Use AnyVersion para escrita incondicional.
+	ExpectedVersion int64
+}
+
+//BulkResult é o resultado de uma operação em lote, na mesma posição da operação.
+type BulkResult struct {
+	//Before é o estado do usuário antes da operação (nil em BulkCreate ou quando a operação falhou).
+	Before *models.User
+	//After é o estado do usuário depois da operação (nil quando a operação falhou).
+	After *models.User
+	//Err é nil quando a operação foi aplicada. Usa os mesmos erros de Create, Update e Delete,
+	//ou ErrBulkSkipped quando a operação não chegou a ser executada.
+	Err error
+}
+
+//Aplica a operação sobre o estado atual do usuário (nil se ele não existir), da mesma forma que Create, Update e Delete,
+//e retorna o novo estado. now é o instante gravado em DeletedAt nas exclusões.
+//Usado pelos dois stores para calcular o resultado de cada operação.
+func applyBulkOperation(op BulkOperation, current *models.User, now time.Time) (models.User, error) {
+	if op.Op == BulkCreate {
+		created := op.User
+		created.Version = 1
+		created.DeletedAt = nil
+		return created, nil
+	}
+	if current == nil || current.DeletedAt != nil {
+		return models.User{}, ErrUserNotFound
+	}
+	if op.ExpectedVersion != AnyVersion && current.Version != op.ExpectedVersion {
+		return models.User{}, ErrVersionConflict
+	}
+	next := *current
+	next.Version++
+	switch op.Op {
+	case BulkUpdate:
+		next.Name = op.User.Name
+		next.Location = op.User.Location
+		next.Title = op.User.Title
+	case BulkDelete:
+		next.DeletedAt = &now
+	}
+	return next, nil
+}
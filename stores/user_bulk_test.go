@@ -0,0 +1,94 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Lote em que a segunda operação falha (versão desatualizada) e as demais seriam válidas.
+func failingBulk(user models.User) []BulkOperation {
+	renamed := user
+	renamed.Name = "Renamed"
+	return []BulkOperation{
+		{Op: BulkCreate, User: models.User{Id: primitive.NewObjectID(), Name: "Bruno", Location: "Porto", Title: "Manager"}, ExpectedVersion: AnyVersion},
+		{Op: BulkUpdate, User: renamed, ExpectedVersion: user.Version + 1},
+		{Op: BulkUpdate, User: renamed, ExpectedVersion: user.Version},
+		{Op: BulkDelete, User: models.User{Id: primitive.NewObjectID()}, ExpectedVersion: AnyVersion},
+	}
+}
+
+func TestBulkWriteOrderedStopsAtFirstFailure(t *testing.T) {
+	store, users := newTestUserStore(t, "Ana")
+	operations := failingBulk(users[0])
+
+	results, err := store.BulkWrite(context.Background(), operations, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErrs := []error{nil, ErrVersionConflict, ErrBulkSkipped, ErrBulkSkipped}
+	for i, want := range wantErrs {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("operation %d error = %v, want %v", i, results[i].Err, want)
+		}
+	}
+
+	//A criação anterior à falha foi aplicada e a edição seguinte, não.
+	if _, err := store.Get(context.Background(), operations[0].User.Id, false); err != nil {
+		t.Errorf("created user: %v", err)
+	}
+	if stored, _ := store.Get(context.Background(), users[0].Id, false); stored.Name != "Ana" || stored.Version != 1 {
+		t.Errorf("user after the skipped update = %+v", stored)
+	}
+}
+
+func TestBulkWriteUnorderedAttemptsEveryOperation(t *testing.T) {
+	store, users := newTestUserStore(t, "Ana")
+	operations := failingBulk(users[0])
+
+	results, err := store.BulkWrite(context.Background(), operations, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErrs := []error{nil, ErrVersionConflict, nil, ErrUserNotFound}
+	for i, want := range wantErrs {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("operation %d error = %v, want %v", i, results[i].Err, want)
+		}
+	}
+
+	updated := results[2]
+	if updated.Before == nil || updated.Before.Version != 1 || updated.After == nil || updated.After.Name != "Renamed" || updated.After.Version != 2 {
+		t.Errorf("update result = %+v", updated)
+	}
+	if stored, _ := store.Get(context.Background(), users[0].Id, false); stored.Name != "Renamed" || stored.Version != 2 {
+		t.Errorf("stored user = %+v", stored)
+	}
+}
+
+func TestBulkWriteChainsOperationsOnTheSameUser(t *testing.T) {
+	store, users := newTestUserStore(t, "Ana")
+	renamed := users[0]
+	renamed.Name = "Renamed"
+
+	//Cada operação parte do estado produzido pela anterior, então a exclusão espera a versão 2.
+	results, err := store.BulkWrite(context.Background(), []BulkOperation{
+		{Op: BulkUpdate, User: renamed, ExpectedVersion: 1},
+		{Op: BulkDelete, User: models.User{Id: users[0].Id}, ExpectedVersion: 2},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Errorf("operation %d error = %v", i, result.Err)
+		}
+	}
+	deleted, err := store.Get(context.Background(), users[0].Id, true)
+	if err != nil || deleted.DeletedAt == nil || deleted.Version != 3 || deleted.Name != "Renamed" {
+		t.Errorf("user after the bulk = %+v, %v", deleted, err)
+	}
+}
@@ -0,0 +1,209 @@
+package stores
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	//DefaultPageSize é o tamanho de página usado quando o cliente não informa um limite.
+	DefaultPageSize = 20
+	//MaxPageSize é o maior tamanho de página aceito pelo servidor; limites maiores são reduzidos para este valor.
+	MaxPageSize = 100
+)
+
+var (
+	//ErrInvalidCursor é retornado quando o cursor recebido não foi gerado por este servidor, está corrompido
+	//ou foi gerado para uma ordenação diferente da consulta atual.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	//ErrInvalidQuery é retornado quando a consulta usa um campo ou operador fora da lista permitida.
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+//FilterOp é um operador de comparação aceito nos filtros da listagem de usuários.
+type FilterOp string
+
+const (
+	//FilterEq seleciona usuários cujo campo é igual ao valor.
+	FilterEq FilterOp = "eq"
+	//FilterNe seleciona usuários cujo campo é diferente do valor.
+	FilterNe FilterOp = "ne"
+	//FilterIn seleciona usuários cujo campo é igual a um dos valores.
+	FilterIn FilterOp = "in"
+	//FilterPrefix seleciona usuários cujo campo começa com o valor.
+	FilterPrefix FilterOp = "prefix"
+)
+
+//Campos de models.User que podem ser usados em filtros e ordenação.
+//A chave é o nome do campo no JSON (que também é o nome no documento do MongoDB) e o valor lê o campo de um usuário.
+var userFields = map[string]func(models.User) string{
+	"name":     func(user models.User) string { return user.Name },
+	"location": func(user models.User) string { return user.Location },
+	"title":    func(user models.User) string { return user.Title },
+}
+
+//IsUserField informa se o campo pode ser usado em filtros e ordenação.
+func IsUserField(field string) bool {
+	_, ok := userFields[field]
+	return ok
+}
+
+//IsFilterOp informa se o operador é aceito nos filtros.
+func IsFilterOp(op FilterOp) bool {
+	switch op {
+	case FilterEq, FilterNe, FilterIn, FilterPrefix:
+		return true
+	}
+	return false
+}
+
+//UserFilter é uma condição sobre um campo de models.User.
+//Todas as condições de uma consulta precisam ser satisfeitas (E lógico).
+type UserFilter struct {
+	Field string
+	Op    FilterOp
+	//Values tem exatamente um valor, exceto para FilterIn.
+	Values []string
+}
+
+//UserSort define a ordenação da listagem. Field vazio ordena apenas pelo ID.
+//O ID é sempre usado como critério de desempate, na mesma direção do campo.
+type UserSort struct {
+	Field      string
+	Descending bool
+}
+
+//Representação textual da ordenação, guardada no cursor (ex.: "-name").
+func (s UserSort) String() string {
+	field := s.Field
+	if field == "" {
+		field = "id"
+	}
+	if s.Descending {
+		return "-" + field
+	}
+	return field
+}
+
+//UserQuery descreve uma página da listagem de usuários.
+type UserQuery struct {
+	//Limit é o número máximo de usuários na página. Zero usa DefaultPageSize.
+	Limit int
+	//Cursor é o valor opaco de UserPage.NextCursor da página anterior. Vazio começa do início.
+	Cursor  string
+	Filters []UserFilter
+	Sort    UserSort
+	//IncludeDeleted inclui na listagem os usuários excluídos logicamente.
+	IncludeDeleted bool
+}
+
+//UserPage é o resultado de uma listagem paginada.
+type UserPage struct {
+	Users []models.User
+	//NextCursor deve ser enviado na próxima consulta para obter a página seguinte. Vazio quando não há mais páginas.
+	NextCursor string
+}
+
+//Conteúdo do cursor antes de ser codificado em base64.
+//A paginação é feita por keyset: a próxima página começa no primeiro usuário que vem depois
+//do par (valor do campo de ordenação, ID) do último usuário retornado.
+type cursorData struct {
+	Sort  string             `json:"s"`
+	Value string             `json:"v,omitempty"`
+	Id    primitive.ObjectID `json:"id"`
+}
+
+//Retorna o limite efetivo da consulta, aplicando o padrão e o teto do servidor.
+func (q UserQuery) pageSize() int {
+	if q.Limit <= 0 {
+		return DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return q.Limit
+}
+
+//Confere se a consulta usa apenas campos e operadores permitidos.
+//Os stores chamam este método antes de traduzir a consulta, para que nada fora da lista chegue ao banco.
+func (q UserQuery) validate() error {
+	for _, filter := range q.Filters {
+		if !IsUserField(filter.Field) {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, filter.Field)
+		}
+		if !IsFilterOp(filter.Op) {
+			return fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, filter.Op)
+		}
+		if len(filter.Values) == 0 || (filter.Op != FilterIn && len(filter.Values) != 1) {
+			return fmt.Errorf("%w: wrong number of values for %s[%s]", ErrInvalidQuery, filter.Field, filter.Op)
+		}
+	}
+	if q.Sort.Field != "" && !IsUserField(q.Sort.Field) {
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, q.Sort.Field)
+	}
+	return nil
+}
+
+//Valor do campo de ordenação de um usuário. Vazio quando a ordenação é apenas pelo ID.
+func (s UserSort) value(user models.User) string {
+	if s.Field == "" {
+		return ""
+	}
+	return userFields[s.Field](user)
+}
+
+func encodeCursor(sort UserSort, user models.User) string {
+	return cursorData{Sort: sort.String(), Value: sort.value(user), Id: user.Id}.encode()
+}
+
+//Codifica o cursor como JSON em base64, o formato opaco entregue ao cliente.
+func (d cursorData) encode() string {
+	raw, _ := json.Marshal(d)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+//Decodifica o cursor recebido do cliente. Um cursor vazio retorna ok = false.
+//O cursor só é aceito com a mesma ordenação usada para gerá-lo.
+func decodeCursor(cursor string, sort UserSort) (cursorData, bool, error) {
+	if cursor == "" {
+		return cursorData{}, false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return cursorData{}, false, ErrInvalidCursor
+	}
+	var data cursorData
+	if err := json.Unmarshal(raw, &data); err != nil || data.Id.IsZero() || data.Sort != sort.String() {
+		return cursorData{}, false, ErrInvalidCursor
+	}
+	return data, true, nil
+}
+
+//Validate confere os campos, operadores e o cursor da consulta sem acessar o banco.
+//Útil antes de Stream, quando os erros precisam ser detectados antes de a resposta começar a ser enviada.
+func (q UserQuery) Validate() error {
+	_, _, err := q.prepare()
+	return err
+}
+
+//Valida a consulta e decodifica o cursor. Usado no início de List e Stream pelos dois stores.
+func (q UserQuery) prepare() (cursorData, bool, error) {
+	if err := q.validate(); err != nil {
+		return cursorData{}, false, err
+	}
+	return decodeCursor(q.Cursor, q.Sort)
+}
+
+//Monta a página a partir de até pageSize+1 usuários: o item excedente indica que existe uma próxima página.
+func newUserPage(users []models.User, pageSize int, sort UserSort) UserPage {
+	if len(users) <= pageSize {
+		return UserPage{Users: users}
+	}
+	users = users[:pageSize]
+	return UserPage{Users: users, NextCursor: encodeCursor(sort, users[len(users)-1])}
+}
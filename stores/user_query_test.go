@@ -0,0 +1,211 @@
+package stores
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//Cria um MemoryUserStore com um usuário para cada nome. Nomes repetidos fazem a ordenação depender do desempate pelo ID.
+func newTestUserStore(t *testing.T, names ...string) (*MemoryUserStore, []models.User) {
+	t.Helper()
+	store := NewMemoryUserStore()
+	users := make([]models.User, len(names))
+	for i, name := range names {
+		users[i] = createNamedUser(t, store, name)
+	}
+	return store, users
+}
+
+func createNamedUser(t *testing.T, store UserStore, name string) models.User {
+	t.Helper()
+	user, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: name, Location: "Lisbon", Title: "Engineer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+//Percorre todas as páginas da consulta seguindo NextCursor e retorna os IDs na ordem em que foram listados.
+func listAllIds(t *testing.T, store UserStore, query UserQuery) []primitive.ObjectID {
+	t.Helper()
+	var ids []primitive.ObjectID
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("pagination does not end")
+		}
+		page, err := store.List(context.Background(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Users) > query.pageSize() {
+			t.Fatalf("page has %d users, want at most %d", len(page.Users), query.pageSize())
+		}
+		for _, user := range page.Users {
+			ids = append(ids, user.Id)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+func TestListPaginatesInSortOrder(t *testing.T) {
+	store, users := newTestUserStore(t, "Carla", "Ana", "Bruno", "Ana", "Carla", "Ana", "Bruno")
+
+	for _, sortBy := range []UserSort{{}, {Descending: true}, {Field: "name"}, {Field: "name", Descending: true}} {
+		t.Run(sortBy.String(), func(t *testing.T) {
+			want := append([]models.User(nil), users...)
+			sort.Slice(want, func(i, j int) bool { return compareUsers(want[i], want[j], sortBy) < 0 })
+			wantIds := make([]primitive.ObjectID, len(want))
+			for i, user := range want {
+				wantIds[i] = user.Id
+			}
+
+			got := listAllIds(t, store, UserQuery{Limit: 2, Sort: sortBy})
+			if !reflect.DeepEqual(got, wantIds) {
+				t.Errorf("listed %v, want %v", got, wantIds)
+			}
+		})
+	}
+}
+
+func TestListCursorIsKeyset(t *testing.T) {
+	store, _ := newTestUserStore(t, "Bruno", "Carla", "Daniel", "Eva")
+	query := UserQuery{Limit: 2, Sort: UserSort{Field: "name"}}
+
+	first, err := store.List(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Users) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+
+	//Um usuário criado antes da posição do cursor não desloca a página seguinte, como aconteceria com skip/offset.
+	createNamedUser(t, store, "Ana")
+	query.Cursor = first.NextCursor
+	second, err := store.List(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, user := range second.Users {
+		names = append(names, user.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Daniel", "Eva"}) || second.NextCursor != "" {
+		t.Errorf("second page = %v (next cursor %q), want [Daniel Eva] and no next cursor", names, second.NextCursor)
+	}
+}
+
+func TestListRejectsInvalidCursor(t *testing.T) {
+	store, _ := newTestUserStore(t, "Ana", "Bruno", "Carla")
+	page, err := store.List(context.Background(), UserQuery{Limit: 1, Sort: UserSort{Field: "name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]UserQuery{
+		"other field":        {Cursor: page.NextCursor, Sort: UserSort{Field: "title"}},
+		"other direction":    {Cursor: page.NextCursor, Sort: UserSort{Field: "name", Descending: true}},
+		"id order":           {Cursor: page.NextCursor},
+		"not base64":         {Cursor: "not a cursor!"},
+		"not json":           {Cursor: base64.RawURLEncoding.EncodeToString([]byte("name"))},
+		"without id":         {Cursor: cursorData{Sort: "id"}.encode()},
+		"truncated encoding": {Cursor: page.NextCursor[:len(page.NextCursor)-3]},
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.List(context.Background(), query); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("List error = %v, want ErrInvalidCursor", err)
+			}
+			if err := query.Validate(); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Validate error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestListPageSize(t *testing.T) {
+	tests := []struct {
+		limit, want int
+	}{
+		{0, DefaultPageSize},
+		{-5, DefaultPageSize},
+		{7, 7},
+		{MaxPageSize + 1, MaxPageSize},
+	}
+	for _, test := range tests {
+		if got := (UserQuery{Limit: test.limit}).pageSize(); got != test.want {
+			t.Errorf("pageSize with limit %d = %d, want %d", test.limit, got, test.want)
+		}
+	}
+}
+
+func TestMongoCursorFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	cursor := cursorData{Value: "Ana", Id: id}
+
+	tests := []struct {
+		sort UserSort
+		want bson.M
+	}{
+		{UserSort{}, bson.M{"id": bson.M{"$gt": id}}},
+		{UserSort{Descending: true}, bson.M{"id": bson.M{"$lt": id}}},
+		{UserSort{Field: "name"}, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$gt": "Ana"}},
+			bson.M{"name": "Ana", "id": bson.M{"$gt": id}},
+		}}},
+		{UserSort{Field: "name", Descending: true}, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$lt": "Ana"}},
+			bson.M{"name": "Ana", "id": bson.M{"$lt": id}},
+		}}},
+	}
+	for _, test := range tests {
+		if got := mongoCursorFilter(test.sort, cursor); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("mongoCursorFilter(%s) = %v, want %v", test.sort, got, test.want)
+		}
+	}
+}
+
+func TestMongoUserFilter(t *testing.T) {
+	filters := []UserFilter{
+		{Field: "location", Op: FilterEq, Values: []string{"Lisbon"}},
+		{Field: "title", Op: FilterNe, Values: []string{"Manager"}},
+		{Field: "title", Op: FilterIn, Values: []string{"Engineer", "Manager"}},
+		//Metacaracteres de expressão regular no prefixo são escapados.
+		{Field: "name", Op: FilterPrefix, Values: []string{"A.*("}},
+	}
+	want := bson.A{
+		bson.M{"location": bson.M{"$eq": "Lisbon"}},
+		bson.M{"title": bson.M{"$ne": "Manager"}},
+		bson.M{"title": bson.M{"$in": []string{"Engineer", "Manager"}}},
+		bson.M{"name": bson.M{"$regex": primitive.Regex{Pattern: `^A\.\*\(`}}},
+	}
+	if got := mongoUserFilter(filters); !reflect.DeepEqual(got, want) {
+		t.Errorf("mongoUserFilter = %v, want %v", got, want)
+	}
+}
+
+func TestUserQueryValidate(t *testing.T) {
+	tests := map[string]UserQuery{
+		"unknown field":    {Filters: []UserFilter{{Field: "password", Op: FilterEq, Values: []string{"x"}}}},
+		"unknown operator": {Filters: []UserFilter{{Field: "name", Op: "$where", Values: []string{"x"}}}},
+		"no values":        {Filters: []UserFilter{{Field: "name", Op: FilterIn}}},
+		"too many values":  {Filters: []UserFilter{{Field: "name", Op: FilterEq, Values: []string{"a", "b"}}}},
+		"unknown sort":     {Sort: UserSort{Field: "deletedAt"}},
+	}
+	for name, query := range tests {
+		if err := query.Validate(); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: Validate error = %v, want ErrInvalidQuery", name, err)
+		}
+	}
+}
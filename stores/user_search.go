@@ -0,0 +1,59 @@
+package stores
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+//Peso de cada campo na busca textual: um termo encontrado no nome vale mais que no cargo, que vale mais que na localização.
+//Os mesmos pesos são usados no índice de texto do MongoDB e na busca em memória.
+var userTextWeights = []struct {
+	field  string
+	weight int
+}{
+	{"name", 3},
+	{"title", 2},
+	{"location", 1},
+}
+
+//Quebra um texto em termos minúsculos e sem acentos, como o índice de texto do MongoDB faz
+//(ex.: "São Paulo" vira ["sao", "paulo"]).
+func searchTerms(text string) []string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+	if err != nil {
+		folded = text
+	}
+	return strings.FieldsFunc(strings.ToLower(folded), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+//Monta o valor de $search do MongoDB com os termos da busca separados por espaço.
+//A normalização de searchTerms remove os operadores do $text (negação com "-" e frases entre aspas), que a busca
+//em memória não implementa; assim os dois stores tratam o texto da mesma forma: uma lista de termos em que basta um corresponder.
+func mongoSearchText(text string) string {
+	return strings.Join(searchTerms(text), " ")
+}
+
+//Calcula a relevância de um usuário para os termos da busca: a soma, para cada termo,
+//do peso de cada campo multiplicado pelo número de vezes que o termo aparece no campo.
+//Zero significa que nenhum termo foi encontrado.
+func searchScore(user models.User, terms []string) float64 {
+	score := 0
+	for _, text := range userTextWeights {
+		fieldTerms := searchTerms(userFields[text.field](user))
+		for _, term := range terms {
+			for _, fieldTerm := range fieldTerms {
+				if fieldTerm == term {
+					score += text.weight
+				}
+			}
+		}
+	}
+	return float64(score)
+}
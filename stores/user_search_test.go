@@ -0,0 +1,62 @@
+package stores
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMongoSearchText(t *testing.T) {
+	tests := map[string]string{
+		"Lisbon":                   "lisbon",
+		"  São   Paulo ":           "sao paulo",
+		"-Lisbon":                  "lisbon",
+		`"Senior Engineer" -Porto`: "senior engineer porto",
+		`-"" -`:                    "",
+	}
+	for text, want := range tests {
+		if got := mongoSearchText(text); got != want {
+			t.Errorf("mongoSearchText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestMemorySearch(t *testing.T) {
+	store := NewMemoryUserStore()
+	create := func(name, location, title string) {
+		if _, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: name, Location: location, Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	create("Ana Lisbon", "Porto", "Engineer")
+	create("Bruno", "Lisbon", "Engineer")
+	create("Carla", "São Paulo", "Lisbon Manager")
+	create("Daniel", "Porto", "Manager")
+
+	tests := map[string][]string{
+		//O peso do campo ordena o resultado: name (3) > title (2) > location (1).
+		"lisbon": {"Ana Lisbon", "Carla", "Bruno"},
+		//"-" e aspas não negam nem agrupam termos, da mesma forma que no MongoUserStore (ver mongoSearchText).
+		"-Lisbon":     {"Ana Lisbon", "Carla", "Bruno"},
+		`"sao paulo"`: {"Carla"},
+		//Empates de relevância são desempatados pelo ID (aqui, a ordem de criação).
+		"manager engineer": {"Ana Lisbon", "Bruno", "Carla", "Daniel"},
+		"--":               {},
+	}
+	for text, want := range tests {
+		users, err := store.Search(context.Background(), text, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, user := range users {
+			got = append(got, user.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
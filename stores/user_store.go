@@ -0,0 +1,62 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	//ErrUserNotFound é retornado pelas implementações de UserStore quando nenhum usuário corresponde ao ID informado.
+	ErrUserNotFound = errors.New("user not found")
+	//ErrVersionConflict é retornado quando a versão armazenada do usuário é diferente da versão esperada,
+	//ou seja, outra requisição alterou o usuário depois que ele foi lido.
+	ErrVersionConflict = errors.New("user version conflict")
+	//ErrUserNotDeleted é retornado por Restore quando o usuário existe mas não está excluído.
+	ErrUserNotDeleted = errors.New("user is not deleted")
+)
+
+//AnyVersion pode ser passado como versão esperada em Update e Delete para ignorar o controle de concorrência.
+const AnyVersion int64 = -1
+
+//UserStore define as operações de persistência de usuários usadas pelos controllers.
+//Existe uma implementação baseada no MongoDB (MongoUserStore) e outra em memória (MemoryUserStore),
+//o que permite trocar o backend ou testar os handlers sem um banco de dados.
+type UserStore interface {
+	//Create grava um novo usuário com a versão 1 e retorna o documento armazenado.
+	Create(ctx context.Context, user models.User) (models.User, error)
+	//Get busca um usuário pelo ID. Retorna ErrUserNotFound se ele não existir
+	//ou se estiver excluído e includeDeleted for false.
+	Get(ctx context.Context, id primitive.ObjectID, includeDeleted bool) (models.User, error)
+	//Update substitui os campos editáveis (name, location, title) do usuário com o mesmo ID, incrementa sua versão
+	//e retorna o documento atualizado. Usuários excluídos não podem ser alterados. Se expectedVersion não for AnyVersion e a versão armazenada for outra, retorna ErrVersionConflict.
+	Update(ctx context.Context, user models.User, expectedVersion int64) (models.User, error)
+	//Delete faz a exclusão lógica (soft delete) do usuário: preenche DeletedAt, incrementa a versão e retorna o documento excluído.
+	//Retorna ErrUserNotFound se ele não existir ou já estiver excluído
+	//e ErrVersionConflict se expectedVersion não for AnyVersion e a versão armazenada for outra.
+	Delete(ctx context.Context, id primitive.ObjectID, expectedVersion int64) (models.User, error)
+	//Restore desfaz a exclusão lógica de um usuário e retorna o documento restaurado.
+	//Retorna ErrUserNotFound se ele não existir e ErrUserNotDeleted se ele não estiver excluído.
+	Restore(ctx context.Context, id primitive.ObjectID) (models.User, error)
+	//Purge remove definitivamente os usuários excluídos antes de deletedBefore e retorna os usuários removidos,
+	//para que cada remoção seja registrada na auditoria. Em caso de erro, retorna também os que já foram removidos.
+	Purge(ctx context.Context, deletedBefore time.Time) ([]models.User, error)
+	//List retorna uma página de usuários (ativos, a menos que query.IncludeDeleted seja true) que satisfazem os filtros da consulta, na ordenação pedida e a partir do cursor.
+	//Retorna ErrInvalidQuery para campos ou operadores fora da lista permitida e ErrInvalidCursor se o cursor não puder ser usado.
+	List(ctx context.Context, query UserQuery) (UserPage, error)
+	//Stream chama fn para cada usuário que satisfaz a consulta, na ordenação pedida e a partir do cursor, sem limite de quantidade.
+	//Os usuários não são acumulados em memória; a iteração para no primeiro erro de fn (ou quando ctx é cancelado) e retorna esse erro.
+	Stream(ctx context.Context, query UserQuery, fn func(models.User) error) error
+	//BulkWrite executa várias operações de criação, edição e exclusão de uma vez e retorna um resultado por operação.
+	//No modo ordenado (ordered = true), as operações são aplicadas em sequência e a primeira falha interrompe as seguintes,
+	//que recebem ErrBulkSkipped; no modo não ordenado, todas são tentadas independentemente.
+	//O erro retornado só é preenchido quando o lote inteiro falha (ex.: banco indisponível).
+	BulkWrite(ctx context.Context, operations []BulkOperation, ordered bool) ([]BulkResult, error)
+	//Search faz uma busca textual em name, title e location dos usuários ativos e retorna até limit usuários, do mais para o menos relevante.
+	//O texto é uma lista de termos, sem diferenciar maiúsculas nem acentos, e basta um termo corresponder; "-" e aspas são
+	//tratados como separadores, e não como negação ou frase.
+	Search(ctx context.Context, text string, limit int) ([]models.User, error)
+}
@@ -0,0 +1,294 @@
+package stores
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//MemoryUserStore implementa UserStore guardando os usuários em um mapa protegido por mutex.
+//Serve para testes e desenvolvimento local, sem depender de um MongoDB.
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+}
+
+//NewMemoryUserStore cria um MemoryUserStore vazio.
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: map[primitive.ObjectID]models.User{}}
+}
+
+func (s *MemoryUserStore) Create(ctx context.Context, user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.Version = 1
+	s.users[user.Id] = user
+	return user, nil
+}
+
+func (s *MemoryUserStore) Get(ctx context.Context, id primitive.ObjectID, includeDeleted bool) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok || (user.DeletedAt != nil && !includeDeleted) {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryUserStore) Update(ctx context.Context, user models.User, expectedVersion int64) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[user.Id]
+	if !ok || stored.DeletedAt != nil {
+		return models.User{}, ErrUserNotFound
+	}
+	if expectedVersion != AnyVersion && stored.Version != expectedVersion {
+		return models.User{}, ErrVersionConflict
+	}
+	stored.Version++
+	stored.Name = user.Name
+	stored.Location = user.Location
+	stored.Title = user.Title
+	s.users[user.Id] = stored
+	return stored, nil
+}
+
+func (s *MemoryUserStore) Delete(ctx context.Context, id primitive.ObjectID, expectedVersion int64) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.DeletedAt != nil {
+		return models.User{}, ErrUserNotFound
+	}
+	if expectedVersion != AnyVersion && stored.Version != expectedVersion {
+		return models.User{}, ErrVersionConflict
+	}
+	now := time.Now()
+	stored.DeletedAt = &now
+	stored.Version++
+	s.users[id] = stored
+	return stored, nil
+}
+
+func (s *MemoryUserStore) Restore(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return models.User{}, ErrUserNotFound
+	}
+	if stored.DeletedAt == nil {
+		return models.User{}, ErrUserNotDeleted
+	}
+	stored.DeletedAt = nil
+	stored.Version++
+	s.users[id] = stored
+	return stored, nil
+}
+
+func (s *MemoryUserStore) Purge(ctx context.Context, deletedBefore time.Time) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged []models.User
+	for id, user := range s.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(deletedBefore) {
+			delete(s.users, id)
+			purged = append(purged, user)
+		}
+	}
+	return purged, nil
+}
+
+func (s *MemoryUserStore) BulkWrite(ctx context.Context, operations []BulkOperation, ordered bool) ([]BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]BulkResult, len(operations))
+	failed := false
+	now := time.Now()
+	for i, op := range operations {
+		if failed && ordered {
+			results[i] = BulkResult{Err: ErrBulkSkipped}
+			continue
+		}
+
+		var current *models.User
+		if stored, ok := s.users[op.User.Id]; ok && op.Op != BulkCreate {
+			current = &stored
+		}
+		next, err := applyBulkOperation(op, current, now)
+		if err != nil {
+			results[i] = BulkResult{Err: err}
+			failed = true
+			continue
+		}
+		s.users[next.Id] = next
+		results[i] = BulkResult{Before: current, After: &next}
+	}
+	return results, nil
+}
+
+func (s *MemoryUserStore) List(ctx context.Context, query UserQuery) (UserPage, error) {
+	users, err := s.query(query)
+	if err != nil {
+		return UserPage{}, err
+	}
+
+	pageSize := query.pageSize()
+	if len(users) > pageSize+1 {
+		users = users[:pageSize+1]
+	}
+	return newUserPage(users, pageSize, query.Sort), nil
+}
+
+func (s *MemoryUserStore) Stream(ctx context.Context, query UserQuery, fn func(models.User) error) error {
+	//Os usuários são copiados antes de chamar fn, para não segurar o lock enquanto o cliente lê a resposta.
+	users, err := s.query(query)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Retorna, ordenados, todos os usuários que satisfazem a consulta a partir do cursor, ignorando o limite.
+func (s *MemoryUserStore) query(query UserQuery) ([]models.User, error) {
+	cursor, ok, err := query.prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]models.User, 0, len(s.users))
+	for _, user := range s.users {
+		if user.DeletedAt != nil && !query.IncludeDeleted {
+			continue
+		}
+		if !matchesUserFilters(user, query.Filters) {
+			continue
+		}
+		//Descarta os usuários que vêm antes do cursor (ou são o próprio cursor) na ordenação.
+		if ok && compareUserToCursor(user, query.Sort, cursor) <= 0 {
+			continue
+		}
+		users = append(users, user)
+	}
+	//Ordena pelo campo pedido e pelo ID para reproduzir a ordenação do MongoUserStore.
+	sort.Slice(users, func(i, j int) bool {
+		return compareUsers(users[i], users[j], query.Sort) < 0
+	})
+	return users, nil
+}
+
+func (s *MemoryUserStore) Search(ctx context.Context, text string, limit int) ([]models.User, error) {
+	terms := searchTerms(text)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type scoredUser struct {
+		user  models.User
+		score float64
+	}
+	matches := []scoredUser{}
+	for _, user := range s.users {
+		if user.DeletedAt != nil {
+			continue
+		}
+		if score := searchScore(user, terms); score > 0 {
+			matches = append(matches, scoredUser{user: user, score: score})
+		}
+	}
+	//Ordena do mais para o menos relevante, desempatando pelo ID como no MongoUserStore.
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return compareIds(matches[i].user.Id, matches[j].user.Id) < 0
+	})
+
+	users := []models.User{}
+	for _, match := range matches {
+		if len(users) == limit {
+			break
+		}
+		users = append(users, match.user)
+	}
+	return users, nil
+}
+
+//Informa se o usuário satisfaz todos os filtros, com a mesma semântica do MongoUserStore.
+func matchesUserFilters(user models.User, filters []UserFilter) bool {
+	for _, filter := range filters {
+		value := userFields[filter.Field](user)
+		var matches bool
+		switch filter.Op {
+		case FilterEq:
+			matches = value == filter.Values[0]
+		case FilterNe:
+			matches = value != filter.Values[0]
+		case FilterIn:
+			for _, candidate := range filter.Values {
+				if value == candidate {
+					matches = true
+					break
+				}
+			}
+		case FilterPrefix:
+			matches = strings.HasPrefix(value, filter.Values[0])
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+//Compara dois usuários na ordenação informada: negativo se a vem antes de b.
+func compareUsers(a, b models.User, sort UserSort) int {
+	return compareSortKeys(sort.value(a), a.Id, sort.value(b), b.Id, sort.Descending)
+}
+
+//Compara um usuário com a posição guardada no cursor: positivo se o usuário vem depois do cursor.
+func compareUserToCursor(user models.User, sort UserSort, cursor cursorData) int {
+	return compareSortKeys(sort.value(user), user.Id, cursor.Value, cursor.Id, sort.Descending)
+}
+
+//Compara os pares (valor, ID) e inverte o resultado em ordenações decrescentes.
+func compareSortKeys(valueA string, idA primitive.ObjectID, valueB string, idB primitive.ObjectID, descending bool) int {
+	result := strings.Compare(valueA, valueB)
+	if result == 0 {
+		result = compareIds(idA, idB)
+	}
+	if descending {
+		return -result
+	}
+	return result
+}
+
+//Compara dois ObjectIDs byte a byte, na mesma ordem usada pelo MongoDB.
+func compareIds(a, b primitive.ObjectID) int {
+	return bytes.Compare(a[:], b[:])
+}
@@ -0,0 +1,516 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//Número de documentos buscados por vez pelo cursor de Stream. Limita a memória usada por exportações grandes.
+const streamBatchSize = 500
+
+//MongoUserStore implementa UserStore sobre uma coleção do MongoDB.
+type MongoUserStore struct {
+	collection *mongo.Collection
+}
+
+//NewMongoUserStore cria um MongoUserStore que usa a coleção informada (normalmente obtida com configs.GetCollection).
+func NewMongoUserStore(collection *mongo.Collection) *MongoUserStore {
+	return &MongoUserStore{collection: collection}
+}
+
+//EnsureIndexes cria os índices usados pelo store, se ainda não existirem.
+//Deve ser chamado na inicialização da aplicação.
+func (s *MongoUserStore) EnsureIndexes(ctx context.Context) error {
+	//Índice de texto usado pela busca (Search), com os mesmos pesos da busca em memória.
+	weights := bson.D{}
+	keys := bson.D{}
+	for _, text := range userTextWeights {
+		keys = append(keys, bson.E{Key: text.field, Value: "text"})
+		weights = append(weights, bson.E{Key: text.field, Value: text.weight})
+	}
+	textIndex := mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName("user_text").SetWeights(weights).SetDefaultLanguage("none"),
+	}
+	_, err := s.collection.Indexes().CreateOne(ctx, textIndex)
+	return err
+}
+
+func (s *MongoUserStore) Create(ctx context.Context, user models.User) (models.User, error) {
+	user.Version = 1
+	if _, err := s.collection.InsertOne(ctx, user); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+//Filtro que seleciona apenas usuários que não foram excluídos logicamente.
+//{"deletedAt": nil} corresponde tanto ao campo ausente quanto ao valor null.
+var activeFilter = bson.E{Key: "deletedAt", Value: nil}
+
+func (s *MongoUserStore) Get(ctx context.Context, id primitive.ObjectID, includeDeleted bool) (models.User, error) {
+	//Os documentos guardam o ObjectID do usuário no campo "id" (e não no "_id" gerado pelo MongoDB).
+	filter := bson.D{{Key: "id", Value: id}}
+	if !includeDeleted {
+		filter = append(filter, activeFilter)
+	}
+	var user models.User
+	err := s.collection.FindOne(ctx, filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, err
+}
+
+func (s *MongoUserStore) Update(ctx context.Context, user models.User, expectedVersion int64) (models.User, error) {
+	update := bson.M{
+		"$set": bson.M{"name": user.Name, "location": user.Location, "title": user.Title},
+		"$inc": bson.M{"version": 1},
+	}
+
+	//FindOneAndUpdate com ReturnDocument(After) aplica a alteração e devolve o documento já atualizado em uma única ida ao banco.
+	//A versão esperada faz parte do filtro, então a verificação e a gravação são atômicas.
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedUser models.User
+	err := s.collection.FindOneAndUpdate(ctx, versionFilter(user.Id, expectedVersion), update, opts).Decode(&updatedUser)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, s.missingOrConflict(ctx, user.Id)
+	}
+	return updatedUser, err
+}
+
+func (s *MongoUserStore) Delete(ctx context.Context, id primitive.ObjectID, expectedVersion int64) (models.User, error) {
+	//A exclusão é lógica: o documento continua na coleção com deletedAt preenchido.
+	update := bson.M{
+		"$set": bson.M{"deletedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var deletedUser models.User
+	err := s.collection.FindOneAndUpdate(ctx, versionFilter(id, expectedVersion), update, opts).Decode(&deletedUser)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, s.missingOrConflict(ctx, id)
+	}
+	return deletedUser, err
+}
+
+func (s *MongoUserStore) Restore(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	update := bson.M{
+		"$unset": bson.M{"deletedAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var restoredUser models.User
+	filter := bson.M{"id": id, "deletedAt": bson.M{"$ne": nil}}
+	err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&restoredUser)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		//Nenhum usuário excluído com esse ID: ou ele não existe ou está ativo.
+		if _, err := s.Get(ctx, id, false); err != nil {
+			return models.User{}, err
+		}
+		return models.User{}, ErrUserNotDeleted
+	}
+	return restoredUser, err
+}
+
+func (s *MongoUserStore) Purge(ctx context.Context, deletedBefore time.Time) ([]models.User, error) {
+	//Os documentos são removidos um a um com FindOneAndDelete (e não com DeleteMany) para que o store devolva
+	//exatamente os usuários que foram removidos, mesmo com outras remoções acontecendo ao mesmo tempo.
+	var purged []models.User
+	for {
+		var user models.User
+		err := s.collection.FindOneAndDelete(ctx, bson.M{"deletedAt": bson.M{"$lt": deletedBefore}}).Decode(&user)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return purged, nil
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged = append(purged, user)
+	}
+}
+
+//Filtro que seleciona o usuário ativo pelo ID e, se expectedVersion não for AnyVersion, também pela versão.
+//Documentos gravados antes do controle de versão não têm o campo e são tratados como versão 0.
+func versionFilter(id primitive.ObjectID, expectedVersion int64) bson.D {
+	filter := bson.D{{Key: "id", Value: id}, activeFilter}
+	if expectedVersion == 0 {
+		filter = append(filter, bson.E{Key: "version", Value: bson.M{"$in": bson.A{0, nil}}})
+	} else if expectedVersion != AnyVersion {
+		filter = append(filter, bson.E{Key: "version", Value: expectedVersion})
+	}
+	return filter
+}
+
+//Quando uma escrita condicional não encontra o documento, descobre se o usuário ativo não existe
+//ou se existe com outra versão.
+func (s *MongoUserStore) missingOrConflict(ctx context.Context, id primitive.ObjectID) error {
+	count, err := s.collection.CountDocuments(ctx, bson.D{{Key: "id", Value: id}, activeFilter}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+	return ErrVersionConflict
+}
+
+func (s *MongoUserStore) List(ctx context.Context, query UserQuery) (UserPage, error) {
+	cursor, ok, err := query.prepare()
+	if err != nil {
+		return UserPage{}, err
+	}
+
+	//Busca um documento a mais que o tamanho da página para saber se existe uma próxima página.
+	pageSize := query.pageSize()
+	conditions, sort := mongoUserQuery(query, cursor, ok)
+	opts := options.Find().SetSort(sort).SetLimit(int64(pageSize + 1))
+	results, err := s.collection.Find(ctx, conditions, opts)
+	if err != nil {
+		return UserPage{}, err
+	}
+	defer results.Close(ctx)
+
+	users := []models.User{}
+	for results.Next(ctx) {
+		var singleUser models.User
+		if err := results.Decode(&singleUser); err != nil {
+			return UserPage{}, err
+		}
+		users = append(users, singleUser)
+	}
+	if err := results.Err(); err != nil {
+		return UserPage{}, err
+	}
+	return newUserPage(users, pageSize, query.Sort), nil
+}
+
+func (s *MongoUserStore) Stream(ctx context.Context, query UserQuery, fn func(models.User) error) error {
+	cursor, ok, err := query.prepare()
+	if err != nil {
+		return err
+	}
+
+	//Sem limite: o driver busca os documentos em lotes conforme o cursor avança,
+	//então só um lote fica em memória por vez, qualquer que seja o tamanho da coleção.
+	conditions, sort := mongoUserQuery(query, cursor, ok)
+	results, err := s.collection.Find(ctx, conditions, options.Find().SetSort(sort).SetBatchSize(streamBatchSize))
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	for results.Next(ctx) {
+		var singleUser models.User
+		if err := results.Decode(&singleUser); err != nil {
+			return err
+		}
+		if err := fn(singleUser); err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
+
+//Monta o filtro e a ordenação do MongoDB para uma consulta já validada, usados por List e Stream.
+func mongoUserQuery(query UserQuery, cursor cursorData, hasCursor bool) (bson.M, bson.D) {
+	filter := mongoUserFilter(query.Filters)
+	if !query.IncludeDeleted {
+		filter = append(filter, bson.D{activeFilter})
+	}
+	if hasCursor {
+		filter = append(filter, mongoCursorFilter(query.Sort, cursor))
+	}
+	conditions := bson.M{}
+	if len(filter) > 0 {
+		conditions["$and"] = filter
+	}
+
+	//Ordena pelo campo pedido e desempata pelo ID, na mesma direção.
+	direction := 1
+	if query.Sort.Descending {
+		direction = -1
+	}
+	sort := bson.D{}
+	if query.Sort.Field != "" {
+		sort = append(sort, bson.E{Key: query.Sort.Field, Value: direction})
+	}
+	sort = append(sort, bson.E{Key: "id", Value: direction})
+	return conditions, sort
+}
+
+//Traduz os filtros já validados para condições do MongoDB.
+//Os valores são sempre comparados como strings literais: o prefixo é escapado com regexp.QuoteMeta
+//para que o cliente não consiga injetar expressões regulares ou operadores.
+func mongoUserFilter(filters []UserFilter) bson.A {
+	conditions := bson.A{}
+	for _, filter := range filters {
+		var condition interface{}
+		switch filter.Op {
+		case FilterEq:
+			condition = bson.M{"$eq": filter.Values[0]}
+		case FilterNe:
+			condition = bson.M{"$ne": filter.Values[0]}
+		case FilterIn:
+			condition = bson.M{"$in": filter.Values}
+		case FilterPrefix:
+			condition = bson.M{"$regex": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.Values[0])}}
+		}
+		conditions = append(conditions, bson.M{filter.Field: condition})
+	}
+	return conditions
+}
+
+//Condição de keyset que seleciona os usuários posteriores ao cursor na ordenação informada.
+func mongoCursorFilter(sort UserSort, cursor cursorData) bson.M {
+	op := "$gt"
+	if sort.Descending {
+		op = "$lt"
+	}
+	if sort.Field == "" {
+		return bson.M{"id": bson.M{op: cursor.Id}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{sort.Field: bson.M{op: cursor.Value}},
+		bson.M{sort.Field: cursor.Value, "id": bson.M{op: cursor.Id}},
+	}}
+}
+
+func (s *MongoUserStore) Search(ctx context.Context, text string, limit int) ([]models.User, error) {
+	//Um texto sem termos (ex.: só pontuação) não corresponde a nenhum usuário, como na busca em memória.
+	search := mongoSearchText(text)
+	if search == "" {
+		return []models.User{}, nil
+	}
+
+	//$text usa o índice "user_text"; a relevância calculada pelo MongoDB (textScore) ordena o resultado.
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "id", Value: 1}}).
+		SetLimit(int64(limit))
+	filter := bson.D{{Key: "$text", Value: bson.M{"$search": search}}, activeFilter}
+	results, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close(ctx)
+
+	users := []models.User{}
+	if err := results.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+//No modo ordenado, as operações são executadas uma a uma (ver bulkWriteOrdered); no não ordenado, em um único BulkWrite do MongoDB.
+func (s *MongoUserStore) BulkWrite(ctx context.Context, operations []BulkOperation, ordered bool) ([]BulkResult, error) {
+	if ordered {
+		return s.bulkWriteOrdered(ctx, operations), nil
+	}
+	results := make([]BulkResult, len(operations))
+
+	//Lê de uma vez os usuários alterados pelo lote. O estado lido é o "antes" de cada operação
+	//e a sua versão entra no filtro da escrita, como em Update e Delete.
+	ids := bson.A{}
+	for _, op := range operations {
+		if op.Op != BulkCreate {
+			ids = append(ids, op.User.Id)
+		}
+	}
+	current := map[primitive.ObjectID]models.User{}
+	if len(ids) > 0 {
+		found, err := s.collection.Find(ctx, bson.D{{Key: "id", Value: bson.M{"$in": ids}}, activeFilter})
+		if err != nil {
+			return nil, err
+		}
+		var users []models.User
+		if err := found.All(ctx, &users); err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			current[user.Id] = user
+		}
+	}
+
+	//Monta os modelos do BulkWrite. Operações que já falham na verificação (usuário inexistente ou versão diferente)
+	//não são enviadas.
+	now := time.Now()
+	var writes []mongo.WriteModel
+	var writeIndexes []int
+	for i, op := range operations {
+		var before *models.User
+		if user, ok := current[op.User.Id]; ok && op.Op != BulkCreate {
+			before = &user
+		}
+		after, err := applyBulkOperation(op, before, now)
+		if err != nil {
+			results[i] = BulkResult{Err: err}
+			continue
+		}
+
+		switch op.Op {
+		case BulkCreate:
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(after))
+		case BulkUpdate:
+			update := bson.M{
+				"$set": bson.M{"name": after.Name, "location": after.Location, "title": after.Title},
+				"$inc": bson.M{"version": 1},
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().SetFilter(versionFilter(op.User.Id, before.Version)).SetUpdate(update))
+		case BulkDelete:
+			update := bson.M{
+				"$set": bson.M{"deletedAt": now},
+				"$inc": bson.M{"version": 1},
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().SetFilter(versionFilter(op.User.Id, before.Version)).SetUpdate(update))
+		}
+		writeIndexes = append(writeIndexes, i)
+		results[i] = BulkResult{Before: before, After: &after}
+		//Operações seguintes sobre o mesmo usuário partem do estado produzido por esta.
+		if op.Op != BulkCreate {
+			current[op.User.Id] = after
+		}
+	}
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	//Executa o lote. Erros de escrita vêm com o índice da operação dentro do lote enviado.
+	_, err := s.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if err != nil && !errors.As(err, &bulkErr) {
+		return nil, err
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		results[writeIndexes[writeErr.Index]] = BulkResult{Err: writeErr}
+	}
+
+	//Uma edição ou exclusão que não casou com o filtro (outra requisição alterou o usuário depois da leitura)
+	//não gera erro de escrita no MongoDB. Por isso os usuários alterados são lidos de novo: a operação foi aplicada
+	//se o documento armazenado é o estado calculado para ela.
+	return results, s.verifyBulkResults(ctx, operations, results)
+}
+
+//Executa um lote ordenado operação por operação, parando na primeira falha.
+//Um BulkWrite ordenado do MongoDB não serve aqui: uma edição cujo filtro de versão não casa (outra requisição alterou
+//o usuário depois da leitura) não é um erro de escrita, então o MongoDB continuaria aplicando as operações seguintes.
+//Com FindOneAndUpdate, cada edição e exclusão verifica a versão e lê o estado anterior de forma atômica,
+//e a falha é conhecida antes de a próxima operação ser enviada. O custo é uma ida ao banco por operação.
+//Falhas do banco (ex.: conexão perdida) também interrompem o lote e ficam no resultado da operação,
+//já que as operações anteriores foram aplicadas.
+func (s *MongoUserStore) bulkWriteOrdered(ctx context.Context, operations []BulkOperation) []BulkResult {
+	results := make([]BulkResult, len(operations))
+	failed := false
+	for i, op := range operations {
+		if failed {
+			results[i] = BulkResult{Err: ErrBulkSkipped}
+			continue
+		}
+		results[i] = s.bulkWriteOne(ctx, op)
+		failed = results[i].Err != nil
+	}
+	return results
+}
+
+//Executa uma operação de um lote ordenado.
+func (s *MongoUserStore) bulkWriteOne(ctx context.Context, op BulkOperation) BulkResult {
+	now := time.Now()
+	if op.Op == BulkCreate {
+		created, _ := applyBulkOperation(op, nil, now)
+		if _, err := s.collection.InsertOne(ctx, created); err != nil {
+			return BulkResult{Err: err}
+		}
+		return BulkResult{After: &created}
+	}
+
+	update := bson.M{
+		"$set": bson.M{"name": op.User.Name, "location": op.User.Location, "title": op.User.Title},
+		"$inc": bson.M{"version": 1},
+	}
+	if op.Op == BulkDelete {
+		update["$set"] = bson.M{"deletedAt": now}
+	}
+	//ReturnDocument(Before) devolve o estado anterior, a partir do qual o novo estado é calculado como no MemoryUserStore.
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	var before models.User
+	err := s.collection.FindOneAndUpdate(ctx, versionFilter(op.User.Id, op.ExpectedVersion), update, opts).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return BulkResult{Err: s.missingOrConflict(ctx, op.User.Id)}
+	}
+	if err != nil {
+		return BulkResult{Err: err}
+	}
+	after, err := applyBulkOperation(op, &before, now)
+	if err != nil {
+		return BulkResult{Err: err}
+	}
+	return BulkResult{Before: &before, After: &after}
+}
+
+//Confere, para cada edição e exclusão dada como aplicada, se o documento armazenado corresponde ao estado calculado.
+//Operações cujo documento ficou diferente recebem ErrVersionConflict.
+func (s *MongoUserStore) verifyBulkResults(ctx context.Context, operations []BulkOperation, results []BulkResult) error {
+	ids := bson.A{}
+	for i, op := range operations {
+		if op.Op != BulkCreate && results[i].Err == nil {
+			ids = append(ids, op.User.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	found, err := s.collection.Find(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err := found.All(ctx, &users); err != nil {
+		return err
+	}
+	stored := map[primitive.ObjectID]models.User{}
+	for _, user := range users {
+		stored[user.Id] = user
+	}
+
+	//Percorre as operações de trás para frente. A última operação sobre cada usuário precisa bater com o documento
+	//armazenado; as anteriores sobre o mesmo usuário foram aplicadas se a seguinte foi, já que o filtro da seguinte
+	//exige a versão produzida pela anterior.
+	applied := map[primitive.ObjectID]bool{}
+	for i := len(operations) - 1; i >= 0; i-- {
+		op, result := operations[i], results[i]
+		if op.Op == BulkCreate || result.Err != nil {
+			continue
+		}
+		ok, seen := applied[op.User.Id]
+		if !seen {
+			user, found := stored[op.User.Id]
+			ok = found && sameUser(user, *result.After)
+			applied[op.User.Id] = ok
+		}
+		if !ok {
+			results[i] = BulkResult{Err: ErrVersionConflict}
+		}
+	}
+	return nil
+}
+
+//Compara os campos gravados por BulkWrite. DeletedAt é comparado em milissegundos, a precisão das datas no MongoDB.
+func sameUser(a, b models.User) bool {
+	if a.Name != b.Name || a.Location != b.Location || a.Title != b.Title || a.Version != b.Version {
+		return false
+	}
+	if a.DeletedAt == nil || b.DeletedAt == nil {
+		return a.DeletedAt == nil && b.DeletedAt == nil
+	}
+	return a.DeletedAt.UnixMilli() == b.DeletedAt.UnixMilli()
+}
@@ -0,0 +1,86 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathanfernande/golang-mongodb-api/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryRestore(t *testing.T) {
+	store, users := newTestUserStore(t, "Ana")
+	id := users[0].Id
+
+	if _, err := store.Restore(context.Background(), id); !errors.Is(err, ErrUserNotDeleted) {
+		t.Errorf("Restore of an active user error = %v, want ErrUserNotDeleted", err)
+	}
+	if _, err := store.Restore(context.Background(), primitive.NewObjectID()); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Restore of a missing user error = %v, want ErrUserNotFound", err)
+	}
+
+	if _, err := store.Delete(context.Background(), id, AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(context.Background(), id, false); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Get of a deleted user error = %v, want ErrUserNotFound", err)
+	}
+	if deleted, err := store.Get(context.Background(), id, true); err != nil || deleted.DeletedAt == nil {
+		t.Errorf("Get with includeDeleted = %+v, %v", deleted, err)
+	}
+
+	restored, err := store.Restore(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.DeletedAt != nil || restored.Version != 3 {
+		t.Errorf("restored user = %+v, want active with version 3", restored)
+	}
+	if _, err := store.Get(context.Background(), id, false); err != nil {
+		t.Errorf("Get of a restored user error = %v", err)
+	}
+}
+
+func TestMemoryPurgeRetention(t *testing.T) {
+	store := NewMemoryUserStore()
+	now := time.Now()
+	create := func(name string, deletedAt *time.Time) models.User {
+		t.Helper()
+		user, err := store.Create(context.Background(), models.User{Id: primitive.NewObjectID(), Name: name, Location: "Lisbon", Title: "Engineer", DeletedAt: deletedAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	cutoff := now.Add(-time.Hour)
+	beforeCutoff := cutoff.Add(-time.Second)
+	atCutoff := cutoff
+	afterCutoff := cutoff.Add(time.Second)
+	active := create("active", nil)
+	old := create("old", &beforeCutoff)
+	exact := create("exact", &atCutoff)
+	recent := create("recent", &afterCutoff)
+
+	purged, err := store.Purge(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purged) != 1 || purged[0].Id != old.Id {
+		t.Errorf("Purge = %+v, want only the user deleted before the cutoff", purged)
+	}
+	//Só os usuários excluídos antes do limite são removidos; o limite em si ainda está dentro da retenção.
+	if _, err := store.Get(context.Background(), old.Id, true); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("user deleted before the cutoff was kept: %v", err)
+	}
+	for _, user := range []models.User{active, exact, recent} {
+		if _, err := store.Get(context.Background(), user.Id, true); err != nil {
+			t.Errorf("user %s was purged: %v", user.Name, err)
+		}
+	}
+
+	if purged, err := store.Purge(context.Background(), cutoff); err != nil || len(purged) != 0 {
+		t.Errorf("second Purge = %+v, %v, want no users", purged, err)
+	}
+}
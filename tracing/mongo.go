@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Identifica um comando em andamento: o RequestID é único por processo, mas o ConnectionID evita colisões entre clientes.
+type commandKey struct {
+	connectionID string
+	requestID    int64
+}
+
+//CommandMonitor retorna o monitor de comandos a ser usado nas opções do cliente do MongoDB (ver configs.ConnectDB).
+//Cada comando vira um span filho do span guardado no contexto da operação (ex.: o contexto derivado de c.UserContext()
+//nos handlers), com o nome "comando coleção" (ex.: "update users"). O conteúdo do comando não é registrado,
+//já que pode conter dados pessoais dos usuários. Comandos fora de uma requisição (ex.: criação de índices e o ping
+//de GET /readyz) não têm span pai e não geram spans, para não encher o exportador de traces de um só span.
+func CommandMonitor(provider trace.TracerProvider) *event.CommandMonitor {
+	tracer := provider.Tracer(ScopeName)
+	var spans sync.Map
+	end := func(e event.CommandFinishedEvent, failure string) {
+		value, ok := spans.LoadAndDelete(commandKey{e.ConnectionID, e.RequestID})
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		if failure != "" {
+			span.SetStatus(codes.Error, failure)
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+			attributes := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBNamespace(e.DatabaseName),
+				semconv.DBOperationName(e.CommandName),
+				semconv.ServerAddress(serverAddress(e.ConnectionID)),
+			}
+			name := e.CommandName
+			if collection := commandCollection(e); collection != "" {
+				name += " " + collection
+				attributes = append(attributes, semconv.DBCollectionName(collection))
+			}
+			_, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+			spans.Store(commandKey{e.ConnectionID, e.RequestID}, span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			end(e.CommandFinishedEvent, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			end(e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
+
+//Retorna a coleção do comando: o valor do próprio comando (ex.: {"find": "users"}) ou, em getMore, o campo collection.
+func commandCollection(e *event.CommandStartedEvent) string {
+	key := e.CommandName
+	if key == "getMore" {
+		key = "collection"
+	}
+	collection, _ := e.Command.Lookup(key).StringValueOK()
+	return collection
+}
+
+//O ConnectionID do driver tem o formato "host:porta[-n]"; retorna apenas "host:porta".
+func serverAddress(connectionID string) string {
+	address, _, _ := strings.Cut(connectionID, "[")
+	return address
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathanfernande/golang-mongodb-api/tracing/tracingtest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func commandStarted(t *testing.T, requestID int64, command bson.D) *event.CommandStartedEvent {
+	t.Helper()
+	raw, err := bson.Marshal(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &event.CommandStartedEvent{Command: raw, DatabaseName: "test", CommandName: command[0].Key, RequestID: requestID, ConnectionID: "localhost:27017[-1]"}
+}
+
+func commandFinished(requestID int64) event.CommandFinishedEvent {
+	return event.CommandFinishedEvent{RequestID: requestID, ConnectionID: "localhost:27017[-1]"}
+}
+
+func TestCommandMonitor(t *testing.T) {
+	provider, exporter := tracingtest.NewInMemoryProvider()
+	monitor := CommandMonitor(provider)
+	ctx, parent := provider.Tracer(ScopeName).Start(context.Background(), "request")
+
+	monitor.Started(ctx, commandStarted(t, 1, bson.D{{Key: "update", Value: "users"}}))
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: commandFinished(1)})
+	monitor.Started(ctx, commandStarted(t, 2, bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "users"}}))
+	monitor.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: commandFinished(2), Failure: "cursor not found"})
+	//Comandos fora de uma requisição não geram spans.
+	monitor.Started(context.Background(), commandStarted(t, 3, bson.D{{Key: "ping", Value: 1}}))
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: commandFinished(3)})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 2 commands and the request", len(spans))
+	}
+	update, getMore := spans[0], spans[1]
+	if update.Name != "update users" || update.Status.Code != codes.Unset {
+		t.Errorf("update span = %q %v", update.Name, update.Status)
+	}
+	if getMore.Name != "getMore users" || getMore.Status.Code != codes.Error || getMore.Status.Description != "cursor not found" {
+		t.Errorf("getMore span = %q %v", getMore.Name, getMore.Status)
+	}
+	for _, span := range spans[:2] {
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s parent = %v, want the request span", span.Name, span.Parent)
+		}
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	tests := map[string]string{
+		"localhost:27017[-1]":     "localhost:27017",
+		"mongo.example:27017[-7]": "mongo.example:27017",
+		"localhost:27017":         "localhost:27017",
+	}
+	for connectionID, want := range tests {
+		if got := serverAddress(connectionID); got != want {
+			t.Errorf("serverAddress(%q) = %q, want %q", connectionID, got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+//Exportadores aceitos em tracing.exporter.
+const (
+	//ExporterOTLP envia os spans por OTLP/HTTP. O destino vem das variáveis padrão do OpenTelemetry
+	//(ex.: OTEL_EXPORTER_OTLP_ENDPOINT); sem elas, usa http://localhost:4318.
+	ExporterOTLP = "otlp"
+	//ExporterStdout escreve os spans em JSON na saída padrão, útil em desenvolvimento.
+	ExporterStdout = "stdout"
+	//ExporterNone não exporta os spans, mas eles continuam sendo criados, então os IDs de trace continuam válidos.
+	ExporterNone = "none"
+)
+
+//Nome do instrumentation scope dos spans criados pela aplicação.
+const ScopeName = "github.com/nathanfernande/golang-mongodb-api"
+
+//NewProvider cria o TracerProvider da aplicação com o exportador informado (ExporterOTLP, ExporterStdout ou ExporterNone).
+//Os spans são enviados em lotes; Shutdown do provider deve ser chamado no encerramento para enviar os que faltam.
+func NewProvider(ctx context.Context, exporter, serviceName string) (*sdktrace.TracerProvider, error) {
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(newResource(serviceName))}
+	switch exporter {
+	case ExporterOTLP:
+		spanExporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	case ExporterStdout:
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	case ExporterNone:
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+	return sdktrace.NewTracerProvider(options...), nil
+}
+
+//Atributos que identificam o serviço em todos os spans.
+func newResource(serviceName string) *resource.Resource {
+	return resource.NewSchemaless(semconv.ServiceName(serviceName))
+}
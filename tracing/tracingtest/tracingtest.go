@@ -0,0 +1,17 @@
+//Package tracingtest tem utilitários para os testes que conferem os spans gerados pela aplicação.
+//Só é importado por arquivos _test.go, então o exportador em memória não vai para o binário da API.
+package tracingtest
+
+import (
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+//NewInMemoryProvider cria um TracerProvider que guarda os spans em memória, para que os testes confiram os spans gerados.
+//Os spans são exportados de forma síncrona: ficam disponíveis em exporter.GetSpans() assim que terminam.
+func NewInMemoryProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("test")))), exporter
+}